@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Activation pairs an activation function with its derivative.
+// Derivative is evaluated on the activated output y = Func(x) unless
+// DerivativeTakesInput is set, in which case it receives the
+// pre-activation input x instead.
+type Activation struct {
+	Name                 string
+	Func                 func(float64) float64
+	Derivative           func(float64) float64
+	DerivativeTakesInput bool
+
+	// rowFunc replaces Func for activations that act on a whole sample
+	// row at once rather than element-wise
+	rowFunc func(*mat.Dense) *mat.Dense
+	// pieces, when positive, makes this a maxout activation over that
+	// many linear pieces per unit
+	pieces int
+}
+
+// Sigmoid squashes inputs into (0, 1). Its derivative takes the output y.
+var Sigmoid = Activation{Name: "sigmoid", Func: sigmoid, Derivative: sigmoidDerivative}
+
+// Tanh squashes inputs into (-1, 1). Its derivative takes the output y.
+// Zero-centered outputs often make it converge faster than Sigmoid in
+// hidden layers.
+var Tanh = Activation{Name: "tanh", Func: tanh, Derivative: tanhDerivative}
+
+// ReLU passes positive inputs through and zeroes the rest. Its derivative
+// takes the pre-activation input x.
+var ReLU = Activation{Name: "relu", Func: relu, Derivative: reluDerivative, DerivativeTakesInput: true}
+
+// Linear is the identity, for output layers of regression networks whose
+// targets are not confined to an activation's range. Its derivative is 1.
+var Linear = Activation{Name: "linear", Func: linear, Derivative: linearDerivative}
+
+// Softplus is log(1 + exp(x)), a smooth approximation of ReLU. Its
+// derivative, the sigmoid, takes the pre-activation input x.
+var Softplus = Activation{Name: "softplus", Func: softplus, Derivative: sigmoid, DerivativeTakesInput: true}
+
+// GELU is the Gaussian error linear unit x * Φ(x), with Φ the standard
+// normal CDF computed exactly from math.Erf. Its derivative takes the
+// pre-activation input x.
+var GELU = Activation{Name: "gelu", Func: gelu, Derivative: geluDerivative, DerivativeTakesInput: true}
+
+// Swish, also known as SiLU, is x * sigmoid(x): smooth, non-monotonic
+// and often better than ReLU in deeper networks. Its derivative takes the
+// pre-activation input x.
+var Swish = Activation{Name: "swish", Func: swish, Derivative: swishDerivative, DerivativeTakesInput: true}
+
+// HardSigmoid is the piecewise-linear clamp(0.2*x + 0.5, 0, 1), a cheap
+// approximation of Sigmoid that needs no exp, for fast inference. Its
+// derivative, 0.2 between -2.5 and 2.5 and 0 outside, takes the
+// pre-activation input x.
+var HardSigmoid = Activation{Name: "hard_sigmoid", Func: hardSigmoid, Derivative: hardSigmoidDerivative, DerivativeTakesInput: true}
+
+// LeakyReLU returns a ReLU variant that scales negative inputs by alpha
+// (typically 0.01) instead of zeroing them, so units cannot die. Its
+// derivative takes the pre-activation input x.
+func LeakyReLU(alpha float64) Activation {
+	return Activation{
+		Name:                 parameterizedName("leaky_relu", alpha),
+		Func:                 leakyRelu(alpha),
+		Derivative:           leakyReluDerivative(alpha),
+		DerivativeTakesInput: true,
+	}
+}
+
+// ELU returns the exponential linear unit: x for x > 0 and
+// alpha * (exp(x) - 1) otherwise, pushing mean activations toward zero.
+// An alpha of 0 selects the usual 1.0. Its derivative takes the
+// pre-activation input x.
+func ELU(alpha float64) Activation {
+	if alpha == 0 {
+		alpha = 1.0
+	}
+	return Activation{
+		Name:                 parameterizedName("elu", alpha),
+		Func:                 elu(alpha),
+		Derivative:           eluDerivative(alpha),
+		DerivativeTakesInput: true,
+	}
+}
+
+// Maxout returns the maxout activation, under which each unit outputs the
+// largest of pieces linear functions of the layer's input, each with its
+// own weights and bias. With enough pieces it can approximate any convex
+// activation. Its layer holds pieces weight rows per unit, so its weight
+// matrix is pieces times taller than usual, and only the winning piece of
+// each unit receives a gradient. It panics unless pieces >= 2.
+func Maxout(pieces int) Activation {
+	if pieces < 2 {
+		panic(fmt.Sprintf("nngo: maxout needs at least 2 pieces, got %d", pieces))
+	}
+	return Activation{Name: parameterizedName("maxout", float64(pieces)), pieces: pieces}
+}
+
+// SoftmaxOutput normalizes each sample's outputs into a probability
+// distribution. It couples the units of a row, so it has no element-wise
+// derivative: it may only be used on the output layer together with the
+// CategoricalCrossEntropy loss, whose combined gradient is pred - target,
+// or SparseCategoricalCrossEntropy.
+var SoftmaxOutput = Activation{Name: "softmax", rowFunc: Softmax}
+
+// activationsByName lets serialized networks refer to activations by Name
+var activationsByName = map[string]Activation{
+	Sigmoid.Name:       Sigmoid,
+	Tanh.Name:          Tanh,
+	ReLU.Name:          ReLU,
+	Softplus.Name:      Softplus,
+	Linear.Name:        Linear,
+	GELU.Name:          GELU,
+	Swish.Name:         Swish,
+	HardSigmoid.Name:   HardSigmoid,
+	SoftmaxOutput.Name: SoftmaxOutput,
+}
+
+// parameterizedActivations builds the activations whose Name records a
+// parameter, as in "leaky_relu(0.01)", reporting whether the parameter is
+// valid
+var parameterizedActivations = map[string]func(float64) (Activation, bool){
+	"leaky_relu": func(alpha float64) (Activation, bool) { return LeakyReLU(alpha), true },
+	"elu":        func(alpha float64) (Activation, bool) { return ELU(alpha), true },
+	"maxout": func(pieces float64) (Activation, bool) {
+		if pieces != math.Trunc(pieces) || pieces < 2 || pieces > math.MaxInt32 {
+			return Activation{}, false
+		}
+		return Maxout(int(pieces)), true
+	},
+}
+
+// parameterizedName formats the Name of an activation with parameter p so
+// that activationByName recovers p exactly
+func parameterizedName(base string, p float64) string {
+	return base + "(" + strconv.FormatFloat(p, 'g', -1, 64) + ")"
+}
+
+// activationByName returns the activation with the given Name
+func activationByName(name string) (Activation, bool) {
+	if a, ok := activationsByName[name]; ok {
+		return a, true
+	}
+	base, param, ok := strings.Cut(name, "(")
+	if !ok || !strings.HasSuffix(param, ")") {
+		return Activation{}, false
+	}
+	build, ok := parameterizedActivations[base]
+	if !ok {
+		return Activation{}, false
+	}
+	p, err := strconv.ParseFloat(strings.TrimSuffix(param, ")"), 64)
+	if err != nil {
+		return Activation{}, false
+	}
+	return build(p)
+}
+
+// inputWidth returns the number of pre-activation values a computes units
+// outputs from: pieces per unit for maxout and one otherwise
+func (a Activation) inputWidth(units int) int {
+	if a.pieces > 0 {
+		return units * a.pieces
+	}
+	return units
+}
+
+// applyInto returns a applied to the pre-activation matrix m, writing into
+// *dst when it already has m's shape, on a single goroutine if serial is
+// set
+func (a Activation) applyInto(dst **mat.Dense, m *mat.Dense, serial bool) *mat.Dense {
+	if a.rowFunc != nil {
+		*dst = a.rowFunc(m)
+		return *dst
+	}
+	r, c := m.Dims()
+	if a.pieces > 0 {
+		result := reuse(dst, r, c/a.pieces)
+		for i := 0; i < r; i++ {
+			out := result.RawRowView(i)
+			row := m.RawRowView(i)
+			for j := range out {
+				out[j] = row[maxoutWinner(row, j, a.pieces)]
+			}
+		}
+		return result
+	}
+	result := reuse(dst, r, c)
+	applyElementwise(result, m, a.Func, serial)
+	return result
+}
+
+// delta returns the error at a's pre-activation input given errors, the
+// error at its output. Maxout routes each unit's error to its winning
+// piece; other activations scale it by their derivative.
+func (a Activation) delta(input, output, errors *mat.Dense, serial bool) *mat.Dense {
+	if a.pieces == 0 {
+		d := a.derivative(input, output, serial)
+		d.MulElem(d, errors)
+		return d
+	}
+	r, c := input.Dims()
+	d := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		row := input.RawRowView(i)
+		for j, e := range errors.RawRowView(i) {
+			d.Set(i, maxoutWinner(row, j, a.pieces), e)
+		}
+	}
+	return d
+}
+
+// maxoutWinner returns the index in row of the largest of unit's pieces,
+// the first one on ties. Unit j's pieces are row[j*pieces : (j+1)*pieces].
+func maxoutWinner(row []float64, unit, pieces int) int {
+	best := unit * pieces
+	for k := best + 1; k < (unit+1)*pieces; k++ {
+		if row[k] > row[best] {
+			best = k
+		}
+	}
+	return best
+}
+
+// derivative applies a's derivative to whichever of the layer's
+// pre-activation input or activated output it expects, on a single
+// goroutine if serial is set
+func (a Activation) derivative(input, output *mat.Dense, serial bool) *mat.Dense {
+	if a.DerivativeTakesInput {
+		return applyActivationDerivative(input, a.Derivative, serial)
+	}
+	return applyActivationDerivative(output, a.Derivative, serial)
+}
+
+// Activation function and its derivative (Sigmoid). exp is only taken of
+// non-positive values so it cannot overflow for inputs of either sign.
+func sigmoid(x float64) float64 {
+	if x >= 0 {
+		return 1.0 / (1.0 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1.0 + e)
+}
+
+func sigmoidDerivative(x float64) float64 {
+	return x * (1.0 - x)
+}
+
+func tanh(x float64) float64 {
+	return math.Tanh(x)
+}
+
+func tanhDerivative(x float64) float64 {
+	return 1.0 - x*x
+}
+
+func relu(x float64) float64 {
+	return math.Max(0, x)
+}
+
+func reluDerivative(x float64) float64 {
+	if x > 0 {
+		return 1.0
+	}
+	return 0.0
+}
+
+func linear(x float64) float64 {
+	return x
+}
+
+func linearDerivative(float64) float64 {
+	return 1.0
+}
+
+// softplusThreshold is where log(1 + exp(x)) equals x to within float64
+// precision, beyond which exp(x) could overflow
+const softplusThreshold = 36
+
+func softplus(x float64) float64 {
+	if x > softplusThreshold {
+		return x
+	}
+	return math.Log1p(math.Exp(x))
+}
+
+func gelu(x float64) float64 {
+	return 0.5 * x * (1 + math.Erf(x/math.Sqrt2))
+}
+
+// geluDerivative is Φ(x) + x * φ(x), with φ the standard normal density
+func geluDerivative(x float64) float64 {
+	cdf := 0.5 * (1 + math.Erf(x/math.Sqrt2))
+	pdf := math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+	return cdf + x*pdf
+}
+
+func swish(x float64) float64 {
+	return x * sigmoid(x)
+}
+
+func swishDerivative(x float64) float64 {
+	s := sigmoid(x)
+	return s + x*s*(1-s)
+}
+
+func hardSigmoid(x float64) float64 {
+	return math.Min(math.Max(0.2*x+0.5, 0), 1)
+}
+
+func hardSigmoidDerivative(x float64) float64 {
+	if x > -2.5 && x < 2.5 {
+		return 0.2
+	}
+	return 0.0
+}
+
+func leakyRelu(alpha float64) func(float64) float64 {
+	return func(x float64) float64 {
+		if x > 0 {
+			return x
+		}
+		return alpha * x
+	}
+}
+
+func leakyReluDerivative(alpha float64) func(float64) float64 {
+	return func(x float64) float64 {
+		if x > 0 {
+			return 1.0
+		}
+		return alpha
+	}
+}
+
+func elu(alpha float64) func(float64) float64 {
+	return func(x float64) float64 {
+		if x > 0 {
+			return x
+		}
+		return alpha * (math.Exp(x) - 1)
+	}
+}
+
+func eluDerivative(alpha float64) func(float64) float64 {
+	return func(x float64) float64 {
+		if x > 0 {
+			return 1.0
+		}
+		return alpha * math.Exp(x)
+	}
+}
+
+// Softmax applies the softmax function to each row of m, turning raw
+// scores such as logits into probabilities that sum to 1. The row maximum
+// is subtracted first so large inputs cannot overflow exp.
+func Softmax(m *mat.Dense) *mat.Dense {
+	r, c := m.Dims()
+	result := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		row := m.RawRowView(i)
+		rowMax := row[0]
+		for _, v := range row[1:] {
+			rowMax = math.Max(rowMax, v)
+		}
+		sum := 0.0
+		for j, v := range row {
+			e := math.Exp(v - rowMax)
+			result.Set(i, j, e)
+			sum += e
+		}
+		for j := 0; j < c; j++ {
+			result.Set(i, j, result.At(i, j)/sum)
+		}
+	}
+	return result
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestConfiguredActivations(t *testing.T) {
+	nn := New([]int{2, 3, 1}, WithSeed(1), WithActivation(Tanh), WithOutputActivation(Linear))
+	input := mat.NewDense(1, 2, []float64{0.3, -0.7})
+
+	// Biases start at zero, so the output is w2 · tanh(w1 · x)
+	w := nn.Weights()
+	hidden := make([]float64, 3)
+	for i := range hidden {
+		hidden[i] = math.Tanh(w[0].At(i, 0)*0.3 + w[0].At(i, 1)*-0.7)
+	}
+	want := 0.0
+	for i, h := range hidden {
+		want += w[1].At(0, i) * h
+	}
+	if got := nn.Predict(input).At(0, 0); math.Abs(got-want) > 1e-12 {
+		t.Errorf("Predict = %v, want %v", got, want)
+	}
+}
+
+func TestReLU(t *testing.T) {
+	for _, tc := range []struct{ x, y, dy float64 }{
+		{-2, 0, 0},
+		{0, 0, 0},
+		{1.5, 1.5, 1},
+	} {
+		if got := relu(tc.x); got != tc.y {
+			t.Errorf("relu(%v) = %v, want %v", tc.x, got, tc.y)
+		}
+		if got := reluDerivative(tc.x); got != tc.dy {
+			t.Errorf("reluDerivative(%v) = %v, want %v", tc.x, got, tc.dy)
+		}
+	}
+}
+
+func TestReLUHiddenLayerLearns(t *testing.T) {
+	inputs, targets := xorData()
+	nn := New([]int{2, 8, 1}, WithSeed(1), WithActivation(ReLU), WithInit(HeNormal))
+	history, err := nn.Train(inputs, targets, 10000, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, last := history[0], history[len(history)-1]; last >= first {
+		t.Errorf("loss went from %v to %v, want it to drop", first, last)
+	}
+}
+
+func TestTanhLearnsXOR(t *testing.T) {
+	inputs, targets := xorData()
+	nn := New([]int{2, 4, 1}, WithSeed(1), WithActivation(Tanh))
+	if _, err := nn.Train(inputs, targets, 10000, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	predictions := nn.Predict(inputs)
+	for i := 0; i < 4; i++ {
+		if got, want := math.Round(predictions.At(i, 0)), targets.At(i, 0); got != want {
+			t.Errorf("sample %d predicted %v, want %v", i, predictions.At(i, 0), want)
+		}
+	}
+}
+
+// threeClassData returns 90 two-feature samples in three clusters with
+// their one-hot targets
+func threeClassData() (inputs, targets *mat.Dense) {
+	centers := [][2]float64{{0, 0}, {3, 0}, {0, 3}}
+	noise := randomDense(90, 2, 1)
+	inputs = mat.NewDense(90, 2, nil)
+	labels := make([]int, 90)
+	for i := range labels {
+		labels[i] = i % 3
+		c := centers[labels[i]]
+		inputs.Set(i, 0, c[0]+noise.At(i, 0))
+		inputs.Set(i, 1, c[1]+noise.At(i, 1))
+	}
+	return inputs, OneHot(labels, 3)
+}
+
+func TestSoftmaxClassifier(t *testing.T) {
+	inputs, targets := threeClassData()
+	nn := New([]int{2, 8, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(CategoricalCrossEntropy{}))
+	if _, err := nn.Train(inputs, targets, 500, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	predictions := nn.Predict(inputs)
+	for i := 0; i < 90; i++ {
+		if sum := mat.Sum(predictions.RowView(i)); math.Abs(sum-1) > 1e-12 {
+			t.Errorf("row %d sums to %v, want 1", i, sum)
+		}
+	}
+	if acc := AccuracyArgmax(predictions, targets); acc < 0.95 {
+		t.Errorf("accuracy %v, want at least 0.95", acc)
+	}
+}
+
+func TestSoftmaxRequiresCrossEntropy(t *testing.T) {
+	inputs, targets := threeClassData()
+	nn := New([]int{2, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput))
+	want := "nngo: softmax output layer requires the CategoricalCrossEntropy or SparseCategoricalCrossEntropy loss"
+	if _, err := nn.Train(inputs, targets, 1, 0.5); err == nil || err.Error() != want {
+		t.Errorf("training softmax with MSE gave error %v, want %q", err, want)
+	}
+	if err := nn.CheckDims(inputs, nil); err != nil {
+		t.Errorf("checking inputs alone: %v", err)
+	}
+}
+
+func TestLeakyReLU(t *testing.T) {
+	a := LeakyReLU(0.01)
+	if got := a.Func(-3); math.Abs(got - -0.03) > 1e-15 {
+		t.Errorf("LeakyReLU(0.01)(-3) = %v, want -0.03", got)
+	}
+	if got := a.Derivative(-3); got != 0.01 {
+		t.Errorf("derivative at -3 = %v, want 0.01", got)
+	}
+	if got := a.Func(2); got != 2 {
+		t.Errorf("LeakyReLU(0.01)(2) = %v, want 2", got)
+	}
+	if got := a.Derivative(2); got != 1 {
+		t.Errorf("derivative at 2 = %v, want 1", got)
+	}
+
+	inputs, targets := xorData()
+	nn := New([]int{2, 4, 1}, WithSeed(1), WithActivation(a))
+	if _, err := nn.Train(inputs, targets, 10, 0.5); err != nil {
+		t.Errorf("training a leaky ReLU hidden layer: %v", err)
+	}
+}
+
+func TestELU(t *testing.T) {
+	a := ELU(0)
+	const h = 1e-9
+	if left, right := a.Func(-h), a.Func(h); math.Abs(left-right) > 1e-8 {
+		t.Errorf("ELU jumps at 0: %v on the left, %v on the right", left, right)
+	}
+	for _, x := range []float64{-0.5, -2, -10} {
+		if got, want := a.Func(x), math.Exp(x)-1; math.Abs(got-want) > 1e-15 {
+			t.Errorf("ELU(%v) = %v, want %v", x, got, want)
+		}
+		if got, want := a.Derivative(x), math.Exp(x); math.Abs(got-want) > 1e-15 {
+			t.Errorf("ELU derivative at %v = %v, want %v", x, got, want)
+		}
+	}
+	if got, want := ELU(0.5).Func(-1), 0.5*(math.Exp(-1)-1); math.Abs(got-want) > 1e-15 {
+		t.Errorf("ELU(0.5)(-1) = %v, want %v", got, want)
+	}
+	if a.Func(3) != 3 || a.Derivative(3) != 1 {
+		t.Errorf("ELU at 3 gave %v with derivative %v, want 3 and 1", a.Func(3), a.Derivative(3))
+	}
+}
+
+func TestSoftplusStable(t *testing.T) {
+	for _, x := range []float64{100, 1000} {
+		if got := softplus(x); math.IsInf(got, 0) || math.Abs(got-x) > 1e-9 {
+			t.Errorf("softplus(%v) = %v, want about %v", x, got, x)
+		}
+	}
+	if got := softplus(-1000); got < 0 || got > 1e-300 {
+		t.Errorf("softplus(-1000) = %v, want about 0", got)
+	}
+	if got, want := softplus(0), math.Log(2); math.Abs(got-want) > 1e-15 {
+		t.Errorf("softplus(0) = %v, want %v", got, want)
+	}
+	if got := Softplus.Derivative(0); got != 0.5 {
+		t.Errorf("softplus derivative at 0 = %v, want sigmoid(0) = 0.5", got)
+	}
+}
+
+func TestSoftmax(t *testing.T) {
+	logits := mat.NewDense(3, 3, []float64{
+		1, 2, 3,
+		-5, 0, 5,
+		1000, 1001, 999,
+	})
+	probs := Softmax(logits)
+	for i := 0; i < 3; i++ {
+		row := probs.RawRowView(i)
+		sum := 0.0
+		for _, p := range row {
+			if math.IsNaN(p) || p < 0 || p > 1 {
+				t.Fatalf("row %d has probability %v", i, p)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-12 {
+			t.Errorf("row %d sums to %v, want 1", i, sum)
+		}
+	}
+	// A constant shift of the logits leaves the probabilities unchanged
+	shifted := Softmax(mat.NewDense(1, 3, []float64{1, 2, 0}))
+	if !mat.EqualApprox(probs.RowView(2), shifted.RowView(0), 1e-12) {
+		t.Errorf("large logits gave %v, want %v", probs.RawRowView(2), shifted.RawRowView(0))
+	}
+}
+
+func TestSigmoidStable(t *testing.T) {
+	for _, tc := range []struct{ x, want float64 }{
+		{-1000, 0},
+		{1000, 1},
+		{0, 0.5},
+		{-40, math.Exp(-40) / (1 + math.Exp(-40))},
+	} {
+		got := sigmoid(tc.x)
+		if math.IsNaN(got) || math.IsInf(got, 0) || math.Abs(got-tc.want) > 1e-15*math.Max(1, tc.want) {
+			t.Errorf("sigmoid(%v) = %v, want %v", tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestGELU(t *testing.T) {
+	if got := gelu(0); got != 0 {
+		t.Errorf("GELU(0) = %v, want 0", got)
+	}
+	if got := gelu(10); math.Abs(got-10) > 1e-12 {
+		t.Errorf("GELU(10) = %v, want about 10", got)
+	}
+	if got := gelu(-10); math.Abs(got) > 1e-12 {
+		t.Errorf("GELU(-10) = %v, want about 0", got)
+	}
+	// The derivative matches a central difference
+	for _, x := range []float64{-2, -0.5, 0, 0.7, 3} {
+		const h = 1e-6
+		numeric := (gelu(x+h) - gelu(x-h)) / (2 * h)
+		if got := geluDerivative(x); math.Abs(got-numeric) > 1e-8 {
+			t.Errorf("GELU derivative at %v = %v, numeric %v", x, got, numeric)
+		}
+	}
+}
+
+func TestSwish(t *testing.T) {
+	if got := swish(0); got != 0 {
+		t.Errorf("Swish(0) = %v, want 0", got)
+	}
+	if got := swishDerivative(0); got != 0.5 {
+		t.Errorf("Swish derivative at 0 = %v, want 0.5", got)
+	}
+	for _, x := range []float64{-3, -1, 0.5, 2} {
+		const h = 1e-6
+		numeric := (swish(x+h) - swish(x-h)) / (2 * h)
+		if got := swishDerivative(x); math.Abs(got-numeric) > 1e-8 {
+			t.Errorf("Swish derivative at %v = %v, numeric %v", x, got, numeric)
+		}
+	}
+}
+
+func TestMaxoutForward(t *testing.T) {
+	nn := New([]int{2, 3, 1}, WithSeed(1), WithInit(GlorotUniform), WithActivation(Maxout(4)))
+	if r, c := nn.weights[0].Dims(); r != 12 || c != 2 {
+		t.Fatalf("maxout weights are %dx%d, want 12x2", r, c)
+	}
+	inputs := randomDense(5, 2, 1)
+	pass := nn.feedforward(inputs, evalMode)
+	z, hidden := pass.preActivations[0], pass.activations[1]
+	if r, c := hidden.Dims(); r != 5 || c != 3 {
+		t.Fatalf("maxout outputs are %dx%d, want 5x3", r, c)
+	}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 3; j++ {
+			want := math.Inf(-1)
+			for k := 0; k < 4; k++ {
+				want = math.Max(want, z.At(i, 4*j+k))
+			}
+			if got := hidden.At(i, j); got != want {
+				t.Errorf("sample %d unit %d = %v, want the largest piece %v", i, j, got, want)
+			}
+		}
+	}
+
+	targets := randomDense(5, 1, 2)
+	if diff := nn.GradientCheck(inputs, targets, 1e-6); diff >= 1e-6 {
+		t.Errorf("maxout gradient relative error %v, want below 1e-6", diff)
+	}
+	assertPanics(t, "Maxout(1)", func() { Maxout(1) })
+}
+
+func TestHardSigmoidClamps(t *testing.T) {
+	for _, tc := range []struct{ x, want, slope float64 }{
+		{-10, 0, 0},
+		{-2.5, 0, 0},
+		{-1, 0.3, 0.2},
+		{0, 0.5, 0.2},
+		{1, 0.7, 0.2},
+		{2.5, 1, 0},
+		{10, 1, 0},
+	} {
+		if got := hardSigmoid(tc.x); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("hardSigmoid(%v) = %v, want %v", tc.x, got, tc.want)
+		}
+		if got := hardSigmoidDerivative(tc.x); got != tc.slope {
+			t.Errorf("hardSigmoidDerivative(%v) = %v, want %v", tc.x, got, tc.slope)
+		}
+	}
+}
+
+func benchmarkActivation(b *testing.B, a Activation) {
+	m := randomDense(1000, 1000, 1)
+	m.Scale(10, m)
+	var dst *mat.Dense
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.applyInto(&dst, m, true)
+	}
+}
+
+func BenchmarkHardSigmoid(b *testing.B) {
+	benchmarkActivation(b, HardSigmoid)
+}
+
+func BenchmarkSigmoid(b *testing.B) {
+	benchmarkActivation(b, Sigmoid)
+}
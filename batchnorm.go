@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	// batchNormMomentum weights the old running statistics against each
+	// new batch's statistics
+	batchNormMomentum = 0.9
+	// batchNormEpsilon keeps the normalization finite for constant units
+	batchNormEpsilon = 1e-5
+)
+
+// batchNorm normalizes a hidden layer's pre-activations unit by unit:
+// y = gamma * (z - mean) / sqrt(variance + epsilon) + beta. Training uses
+// each batch's mean and variance and folds them into running averages,
+// which inference uses instead.
+type batchNorm struct {
+	// gamma and beta are the learnable 1 x n scale and shift
+	gamma, beta *mat.Dense
+	// runningMean and runningVar are the 1 x n statistics used by Predict
+	runningMean, runningVar *mat.Dense
+}
+
+// batchNormCache records what backward needs from one forward call
+type batchNormCache struct {
+	// normalized is (z - mean) / sqrt(variance + epsilon)
+	normalized *mat.Dense
+	// invStd is 1 / sqrt(variance + epsilon) per unit
+	invStd []float64
+	// batchStats tells whether mean and variance came from the batch
+	batchStats bool
+}
+
+func newBatchNorm(units int) *batchNorm {
+	gamma := mat.NewDense(1, units, nil)
+	runningVar := mat.NewDense(1, units, nil)
+	for j := 0; j < units; j++ {
+		gamma.Set(0, j, 1)
+		runningVar.Set(0, j, 1)
+	}
+	return &batchNorm{
+		gamma:       gamma,
+		beta:        mat.NewDense(1, units, nil),
+		runningMean: mat.NewDense(1, units, nil),
+		runningVar:  runningVar,
+	}
+}
+
+// clone returns a deep copy of bn
+func (bn *batchNorm) clone() *batchNorm {
+	return &batchNorm{
+		gamma:       mat.DenseCopyOf(bn.gamma),
+		beta:        mat.DenseCopyOf(bn.beta),
+		runningMean: mat.DenseCopyOf(bn.runningMean),
+		runningVar:  mat.DenseCopyOf(bn.runningVar),
+	}
+}
+
+// copyFrom overwrites bn's parameters and statistics with src's, keeping
+// bn's matrices so optimizer state stays attached
+func (bn *batchNorm) copyFrom(src *batchNorm) {
+	bn.gamma.Copy(src.gamma)
+	bn.beta.Copy(src.beta)
+	bn.runningMean.Copy(src.runningMean)
+	bn.runningVar.Copy(src.runningVar)
+}
+
+// forward normalizes z, using and accumulating batch statistics in
+// trainMode and the running statistics otherwise
+func (bn *batchNorm) forward(z *mat.Dense, m mode) (*mat.Dense, *batchNormCache) {
+	training := m == trainMode
+	r, c := z.Dims()
+	cache := &batchNormCache{
+		normalized: mat.NewDense(r, c, nil),
+		invStd:     make([]float64, c),
+		batchStats: training,
+	}
+	y := mat.NewDense(r, c, nil)
+
+	for j := 0; j < c; j++ {
+		var mean, variance float64
+		if training {
+			for i := 0; i < r; i++ {
+				mean += z.At(i, j)
+			}
+			mean /= float64(r)
+			for i := 0; i < r; i++ {
+				d := z.At(i, j) - mean
+				variance += d * d
+			}
+			variance /= float64(r)
+
+			bn.runningMean.Set(0, j, batchNormMomentum*bn.runningMean.At(0, j)+(1-batchNormMomentum)*mean)
+			bn.runningVar.Set(0, j, batchNormMomentum*bn.runningVar.At(0, j)+(1-batchNormMomentum)*variance)
+		} else {
+			mean, variance = bn.runningMean.At(0, j), bn.runningVar.At(0, j)
+		}
+
+		invStd := 1 / math.Sqrt(variance+batchNormEpsilon)
+		cache.invStd[j] = invStd
+		gamma, beta := bn.gamma.At(0, j), bn.beta.At(0, j)
+		for i := 0; i < r; i++ {
+			n := (z.At(i, j) - mean) * invStd
+			cache.normalized.Set(i, j, n)
+			y.Set(i, j, gamma*n+beta)
+		}
+	}
+	return y, cache
+}
+
+// backward turns the loss gradient with respect to forward's output into
+// the gradients with respect to its input z and to gamma and beta
+func (bn *batchNorm) backward(dy *mat.Dense, cache *batchNormCache) (dz, dGamma, dBeta *mat.Dense) {
+	r, c := dy.Dims()
+	dz = mat.NewDense(r, c, nil)
+	dGamma = mat.NewDense(1, c, nil)
+	dBeta = mat.NewDense(1, c, nil)
+
+	for j := 0; j < c; j++ {
+		var sumDy, sumDyNorm float64
+		for i := 0; i < r; i++ {
+			sumDy += dy.At(i, j)
+			sumDyNorm += dy.At(i, j) * cache.normalized.At(i, j)
+		}
+		dGamma.Set(0, j, sumDyNorm)
+		dBeta.Set(0, j, sumDy)
+
+		scale := bn.gamma.At(0, j) * cache.invStd[j]
+		for i := 0; i < r; i++ {
+			if cache.batchStats {
+				// The batch mean and variance depend on every sample
+				n := float64(r)
+				dz.Set(i, j, scale/n*(n*dy.At(i, j)-sumDy-cache.normalized.At(i, j)*sumDyNorm))
+			} else {
+				dz.Set(i, j, scale*dy.At(i, j))
+			}
+		}
+	}
+	return dz, dGamma, dBeta
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBatchNormKeepsActivationsScaled(t *testing.T) {
+	// Features far from zero mean and unit variance push unnormalized
+	// sigmoid units into saturation
+	inputs := randomDense(64, 3, 1)
+	inputs.Apply(func(_, _ int, v float64) float64 { return 100*v + 50 }, inputs)
+	targets := mat.NewDense(64, 1, nil)
+	for i := 0; i < 64; i++ {
+		if inputs.At(i, 0) > inputs.At(i, 1) {
+			targets.Set(i, 0, 1)
+		}
+	}
+	nn := New([]int{3, 8, 1}, WithSeed(1), WithInit(GlorotUniform), WithBatchNorm())
+	for round := 0; round < 5; round++ {
+		if _, err := nn.Train(inputs, targets, 100, 0.1); err != nil {
+			t.Fatal(err)
+		}
+		hidden := nn.feedforward(inputs, evalMode).preActivations[0]
+		for j := 0; j < 8; j++ {
+			mean, variance := weightStats(mat.DenseCopyOf(hidden.ColView(j)))
+			if std := math.Sqrt(variance); math.Abs(mean) > 0.5 || std < 0.5 || std > 2 {
+				t.Errorf("after %d epochs unit %d has mean %v and std %v", 100*(round+1), j, mean, std)
+			}
+		}
+	}
+}
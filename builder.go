@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Builder assembles a network layer by layer:
+//
+//	nn, err := NewBuilder().Input(2).Hidden(8, Tanh).Hidden(8, ReLU).Output(1, Sigmoid).Build()
+//
+// Mistakes such as a missing input or output layer are reported by Build.
+type Builder struct {
+	sizes       []int
+	activations []Activation
+	hasOutput   bool
+	opts        []Option
+	err         error
+}
+
+// NewBuilder returns an empty Builder
+func NewBuilder() *Builder {
+	return new(Builder)
+}
+
+// Input sets the number of input features. It must be the first call.
+func (b *Builder) Input(size int) *Builder {
+	if len(b.sizes) > 0 {
+		b.fail(errors.New("nngo: builder input layer must come first and only once"))
+	}
+	b.sizes = append(b.sizes, size)
+	return b
+}
+
+// Hidden appends a hidden layer of size units using activation
+func (b *Builder) Hidden(size int, activation Activation) *Builder {
+	return b.layer(size, activation)
+}
+
+// Output appends the output layer of size units using activation. It must
+// be the last layer.
+func (b *Builder) Output(size int, activation Activation) *Builder {
+	b.layer(size, activation)
+	b.hasOutput = true
+	return b
+}
+
+// With adds options such as WithSeed or WithOptimizer for Build to pass to
+// New. WithActivation and WithOutputActivation are overridden by the
+// activations of the layers.
+func (b *Builder) With(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+func (b *Builder) layer(size int, activation Activation) *Builder {
+	switch {
+	case len(b.sizes) == 0:
+		b.fail(errors.New("nngo: builder needs an input layer before other layers"))
+	case b.hasOutput:
+		b.fail(errors.New("nngo: builder has a layer after the output layer"))
+	}
+	b.sizes = append(b.sizes, size)
+	b.activations = append(b.activations, activation)
+	return b
+}
+
+// fail records the first error
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build validates the layers and returns the network they describe
+func (b *Builder) Build() (*NeuralNetwork, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if !b.hasOutput {
+		return nil, errors.New("nngo: builder has no output layer")
+	}
+	for i, size := range b.sizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("nngo: layer %d has size %d, must be positive", i, size)
+		}
+	}
+	last := len(b.activations) - 1
+	for l, activation := range b.activations {
+		if activation.Func == nil && activation.rowFunc == nil && activation.pieces == 0 {
+			return nil, fmt.Errorf("nngo: layer %d has no activation", l+1)
+		}
+		if activation.rowFunc != nil && l != last {
+			return nil, fmt.Errorf("nngo: %s can only be used as the output activation", activation.Name)
+		}
+	}
+
+	nn := New(b.sizes, b.opts...)
+	for l, activation := range b.activations {
+		nn.setActivation(l, activation)
+	}
+	return nn, nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	nn, err := NewBuilder().Input(2).Hidden(8, Tanh).Hidden(8, ReLU).Output(1, Sigmoid).With(WithSeed(1)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSizes := []int{2, 8, 8, 1}
+	if len(nn.layerSizes) != len(wantSizes) {
+		t.Fatalf("layer sizes %v, want %v", nn.layerSizes, wantSizes)
+	}
+	for i, size := range wantSizes {
+		if nn.layerSizes[i] != size {
+			t.Errorf("layer %d has size %d, want %d", i, nn.layerSizes[i], size)
+		}
+	}
+	for l, want := range []string{"tanh", "relu", "sigmoid"} {
+		if got := nn.activations[l].Name; got != want {
+			t.Errorf("layer %d activation %q, want %q", l+1, got, want)
+		}
+	}
+
+	for name, b := range map[string]*Builder{
+		"no input":           NewBuilder().Hidden(2, Tanh).Output(1, Sigmoid),
+		"no output":          NewBuilder().Input(2).Hidden(2, Tanh),
+		"layer after output": NewBuilder().Input(2).Output(1, Sigmoid).Hidden(2, Tanh),
+		"zero size":          NewBuilder().Input(2).Hidden(0, Tanh).Output(1, Sigmoid),
+		"hidden softmax":     NewBuilder().Input(2).Hidden(2, SoftmaxOutput).Output(1, Sigmoid),
+	} {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("%s: Build gave no error", name)
+		}
+	}
+}
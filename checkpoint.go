@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkpointName is the file name of the checkpoint written after the
+// given number of epochs. Zero padding makes names sort by epoch.
+func checkpointName(epochs int) string {
+	return fmt.Sprintf("checkpoint-%08d.json", epochs)
+}
+
+// writeCheckpoint saves the network with SaveJSON after the given number
+// of epochs. The file is written under a temporary name and renamed into
+// place, so a crash never leaves a partial checkpoint behind.
+func (nn *NeuralNetwork) writeCheckpoint(epochs int) error {
+	tmp, err := os.CreateTemp(nn.checkpointDir, ".checkpoint-*.tmp")
+	if err != nil {
+		return fmt.Errorf("nngo: creating checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := nn.SaveJSON(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("nngo: writing checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("nngo: writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(nn.checkpointDir, checkpointName(epochs))); err != nil {
+		return fmt.Errorf("nngo: saving checkpoint: %w", err)
+	}
+	return nil
+}
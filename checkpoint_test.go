@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	inputs, targets := xorData()
+	nn := New([]int{2, 3, 1}, WithSeed(1), WithCheckpoints(25, dir))
+	if _, err := nn.Train(inputs, targets, 100, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("got %d files in the checkpoint directory, want 4", len(entries))
+	}
+	for _, epochs := range []int{25, 50, 75, 100} {
+		f, err := os.Open(filepath.Join(dir, checkpointName(epochs)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		loaded, err := LoadJSON(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("loading the epoch %d checkpoint: %v", epochs, err)
+			continue
+		}
+		if epochs == 100 {
+			assertSamePredictions(t, loaded, nn)
+		}
+	}
+}
+
+func TestCloneWritesNoCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	inputs, targets := xorData()
+	clone := New([]int{2, 3, 1}, WithSeed(1), WithCheckpoints(1, dir)).Clone()
+	if _, err := clone.Train(inputs, targets, 3, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("training the clone left %d files in the original's checkpoint directory (%v)", len(entries), err)
+	}
+}
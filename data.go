@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// MinMaxNormalize scales each column of m independently into [0, 1] and
+// returns the result along with every column's minimum and maximum, so the
+// same scaling can be applied to new data with ApplyMinMax. Constant
+// columns map to 0.
+func MinMaxNormalize(m *mat.Dense) (normalized *mat.Dense, min, max []float64) {
+	r, c := m.Dims()
+	min = make([]float64, c)
+	max = make([]float64, c)
+	for j := 0; j < c; j++ {
+		min[j], max[j] = m.At(0, j), m.At(0, j)
+		for i := 1; i < r; i++ {
+			v := m.At(i, j)
+			if v < min[j] {
+				min[j] = v
+			}
+			if v > max[j] {
+				max[j] = v
+			}
+		}
+	}
+	return ApplyMinMax(m, min, max), min, max
+}
+
+// ApplyMinMax scales each column j of m by (v - min[j]) / (max[j] - min[j])
+// using parameters from MinMaxNormalize. Values outside the original range
+// fall outside [0, 1].
+func ApplyMinMax(m *mat.Dense, min, max []float64) *mat.Dense {
+	r, c := m.Dims()
+	if len(min) != c || len(max) != c {
+		panic(fmt.Sprintf("nngo: matrix has %d columns, got %d minimums and %d maximums", c, len(min), len(max)))
+	}
+	result := mat.NewDense(r, c, nil)
+	for j := 0; j < c; j++ {
+		span := max[j] - min[j]
+		for i := 0; i < r; i++ {
+			if span != 0 {
+				result.Set(i, j, (m.At(i, j)-min[j])/span)
+			}
+		}
+	}
+	return result
+}
+
+// Standardize transforms each column of m independently to zero mean and
+// unit variance (population standard deviation) and returns the result
+// along with every column's mean and standard deviation for use with
+// ApplyStandardize. A constant column gets std 1, so it is only centered.
+func Standardize(m *mat.Dense) (out *mat.Dense, mean, std []float64) {
+	r, c := m.Dims()
+	mean = make([]float64, c)
+	std = make([]float64, c)
+	for j := 0; j < c; j++ {
+		for i := 0; i < r; i++ {
+			mean[j] += m.At(i, j)
+		}
+		mean[j] /= float64(r)
+
+		variance := 0.0
+		for i := 0; i < r; i++ {
+			d := m.At(i, j) - mean[j]
+			variance += d * d
+		}
+		std[j] = math.Sqrt(variance / float64(r))
+		if std[j] == 0 {
+			std[j] = 1
+		}
+	}
+	return ApplyStandardize(m, mean, std), mean, std
+}
+
+// ApplyStandardize maps each column j of m to (v - mean[j]) / std[j] using
+// parameters from Standardize
+func ApplyStandardize(m *mat.Dense, mean, std []float64) *mat.Dense {
+	r, c := m.Dims()
+	if len(mean) != c || len(std) != c {
+		panic(fmt.Sprintf("nngo: matrix has %d columns, got %d means and %d standard deviations", c, len(mean), len(std)))
+	}
+	result := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			result.Set(i, j, (m.At(i, j)-mean[j])/std[j])
+		}
+	}
+	return result
+}
+
+// MinMaxNormalizeColumns is MinMaxNormalize restricted to the listed
+// columns, for data mixing continuous features with ones that should stay
+// as they are, such as binary flags. The other columns are copied
+// unchanged and get a minimum of 0 and a maximum of 1, so ApplyMinMax with
+// the returned parameters leaves them unchanged in new data too. It panics
+// on a column index outside m.
+func MinMaxNormalizeColumns(m *mat.Dense, columns []int) (normalized *mat.Dense, min, max []float64) {
+	_, min, max = MinMaxNormalize(m)
+	for j := range unlistedColumns(m, columns) {
+		min[j], max[j] = 0, 1
+	}
+	return ApplyMinMax(m, min, max), min, max
+}
+
+// StandardizeColumns is Standardize restricted to the listed columns. The
+// other columns are copied unchanged and get a mean of 0 and a standard
+// deviation of 1, so ApplyStandardize with the returned parameters leaves
+// them unchanged in new data too. It panics on a column index outside m.
+func StandardizeColumns(m *mat.Dense, columns []int) (out *mat.Dense, mean, std []float64) {
+	_, mean, std = Standardize(m)
+	for j := range unlistedColumns(m, columns) {
+		mean[j], std[j] = 0, 1
+	}
+	return ApplyStandardize(m, mean, std), mean, std
+}
+
+// unlistedColumns returns the set of m's columns missing from columns,
+// panicking on an index outside m
+func unlistedColumns(m *mat.Dense, columns []int) map[int]bool {
+	_, c := m.Dims()
+	unlisted := make(map[int]bool, c)
+	for j := 0; j < c; j++ {
+		unlisted[j] = true
+	}
+	for _, j := range columns {
+		if j < 0 || j >= c {
+			panic(fmt.Sprintf("nngo: column %d outside a matrix with %d columns", j, c))
+		}
+		delete(unlisted, j)
+	}
+	return unlisted
+}
+
+// ValidateInputs reports the first NaN or infinite cell of inputs, then of
+// targets, by row and column counting from 0, such as a missing value
+// loaded as NaN. targets may be nil.
+func ValidateInputs(inputs, targets *mat.Dense) error {
+	for _, data := range []struct {
+		name string
+		m    *mat.Dense
+	}{{"input", inputs}, {"target", targets}} {
+		if data.m == nil {
+			continue
+		}
+		r, c := data.m.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if v := data.m.At(i, j); math.IsNaN(v) || math.IsInf(v, 0) {
+					return fmt.Errorf("nngo: %s row %d column %d is %v", data.name, i, j, v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// TrainTestSplit shuffles the rows of inputs and targets with a generator
+// seeded by seed and splits them into a training set and a test set holding
+// round(testFraction * rows) samples. Input and target rows stay aligned.
+// It panics unless 0 < testFraction < 1 and both sets get at least one
+// row.
+func TrainTestSplit(inputs, targets *mat.Dense, testFraction float64, seed int64) (trainIn, trainTgt, testIn, testTgt *mat.Dense) {
+	if testFraction <= 0 || testFraction >= 1 {
+		panic(fmt.Sprintf("nngo: test fraction %v outside (0, 1)", testFraction))
+	}
+	rows, _ := inputs.Dims()
+	if targetRows, _ := targets.Dims(); targetRows != rows {
+		panic(fmt.Sprintf("nngo: inputs have %d rows but targets have %d", rows, targetRows))
+	}
+
+	perm := permutation(rows, rand.New(rand.NewSource(seed)))
+	numTest := int(math.Round(testFraction * float64(rows)))
+	if numTest == 0 || numTest == rows {
+		panic(fmt.Sprintf("nngo: test fraction %v of %d rows leaves an empty training or test set", testFraction, rows))
+	}
+	testRows, trainRows := perm[:numTest], perm[numTest:]
+
+	return selectRows(inputs, trainRows), selectRows(targets, trainRows),
+		selectRows(inputs, testRows), selectRows(targets, testRows)
+}
+
+// KFoldCrossValidate splits the rows of inputs and targets into k
+// contiguous folds, trains a network with trainFn on every combination of
+// k-1 folds and scores it with scoreFn on the remaining one, returning the
+// k scores in fold order. When k does not divide the rows the first
+// rows%k folds get one extra row. Shuffle the rows first if they are
+// ordered. It panics unless 2 <= k <= rows.
+func KFoldCrossValidate(inputs, targets *mat.Dense, k int, trainFn func(trIn, trTgt *mat.Dense) *NeuralNetwork, scoreFn func(nn *NeuralNetwork, teIn, teTgt *mat.Dense) float64) []float64 {
+	rows, _ := inputs.Dims()
+	if targetRows, _ := targets.Dims(); targetRows != rows {
+		panic(fmt.Sprintf("nngo: inputs have %d rows but targets have %d", rows, targetRows))
+	}
+	if k < 2 || k > rows {
+		panic(fmt.Sprintf("nngo: %d folds outside [2, %d]", k, rows))
+	}
+
+	scores := make([]float64, k)
+	start := 0
+	for fold := 0; fold < k; fold++ {
+		size := rows / k
+		if fold < rows%k {
+			size++
+		}
+		var trainRows, testRows []int
+		for i := 0; i < rows; i++ {
+			if i >= start && i < start+size {
+				testRows = append(testRows, i)
+			} else {
+				trainRows = append(trainRows, i)
+			}
+		}
+		start += size
+
+		nn := trainFn(selectRows(inputs, trainRows), selectRows(targets, trainRows))
+		scores[fold] = scoreFn(nn, selectRows(inputs, testRows), selectRows(targets, testRows))
+	}
+	return scores
+}
+
+// LoadCSV parses comma-separated numeric records from r and returns the
+// featureCols of every record as inputs and the targetCols as targets, in
+// the order given. The data must not have a header row. Every record must
+// have the same number of fields, and every selected cell must parse as a
+// float once surrounding spaces are trimmed. At least one feature and one
+// target column must be given.
+func LoadCSV(r io.Reader, featureCols, targetCols []int) (inputs, targets *mat.Dense, err error) {
+	if len(featureCols) == 0 || len(targetCols) == 0 {
+		return nil, nil, fmt.Errorf("nngo: %d feature and %d target columns, need at least one of each", len(featureCols), len(targetCols))
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("nngo: reading CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("nngo: CSV has no records")
+	}
+	numFields := len(records[0])
+	for _, col := range append(append([]int(nil), featureCols...), targetCols...) {
+		if col < 0 || col >= numFields {
+			return nil, nil, fmt.Errorf("nngo: column %d out of range, records have %d fields", col, numFields)
+		}
+	}
+
+	inputs = mat.NewDense(len(records), len(featureCols), nil)
+	targets = mat.NewDense(len(records), len(targetCols), nil)
+	for i, record := range records {
+		if err := setCSVRow(inputs, i, record, featureCols); err != nil {
+			return nil, nil, err
+		}
+		if err := setCSVRow(targets, i, record, targetCols); err != nil {
+			return nil, nil, err
+		}
+	}
+	return inputs, targets, nil
+}
+
+// setCSVRow parses record's cols into row i of m
+func setCSVRow(m *mat.Dense, i int, record []string, cols []int) error {
+	for j, col := range cols {
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64)
+		if err != nil {
+			return fmt.Errorf("nngo: record %d column %d: %w", i+1, col, err)
+		}
+		m.Set(i, j, v)
+	}
+	return nil
+}
+
+// OneHot returns a len(labels) x numClasses matrix with a 1 in column
+// labels[i] of row i and 0 elsewhere. It panics on labels outside
+// [0, numClasses).
+func OneHot(labels []int, numClasses int) *mat.Dense {
+	m := mat.NewDense(len(labels), numClasses, nil)
+	for i, label := range labels {
+		if label < 0 || label >= numClasses {
+			panic(fmt.Sprintf("nngo: label %d at row %d outside [0, %d)", label, i, numClasses))
+		}
+		m.Set(i, label, 1)
+	}
+	return m
+}
+
+// SmoothLabels softens class targets for label smoothing: every value t
+// becomes t*(1-epsilon) + epsilon/K, so one-hot rows map 1 to
+// 1-epsilon+epsilon/K and 0 to epsilon/K. K is the number of columns, or 2
+// for a single column of binary targets. It panics unless
+// 0 <= epsilon < 1.
+func SmoothLabels(targets *mat.Dense, epsilon float64) *mat.Dense {
+	if epsilon < 0 || epsilon >= 1 {
+		panic(fmt.Sprintf("nngo: label smoothing %v outside [0, 1)", epsilon))
+	}
+	r, c := targets.Dims()
+	classes := max(c, 2)
+	smoothed := mat.NewDense(r, c, nil)
+	smoothed.Apply(func(i, j int, t float64) float64 {
+		return t + epsilon*(1/float64(classes)-t)
+	}, targets)
+	return smoothed
+}
+
+// ArgmaxRows returns the column index of the largest value in each row of
+// m, turning one-hot rows or class scores back into labels
+func ArgmaxRows(m *mat.Dense) []int {
+	r, _ := m.Dims()
+	labels := make([]int, r)
+	for i := range labels {
+		labels[i] = argmax(m.RawRowView(i))
+	}
+	return labels
+}
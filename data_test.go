@@ -0,0 +1,242 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// featureData returns 20 rows of three columns on very different scales
+func featureData() *mat.Dense {
+	m := randomDense(20, 3, 1)
+	for i := 0; i < 20; i++ {
+		m.Set(i, 1, 1000*m.At(i, 1)-500)
+		m.Set(i, 2, 0.01*m.At(i, 2))
+	}
+	return m
+}
+
+func TestMinMaxNormalize(t *testing.T) {
+	m := featureData()
+	normalized, min, max := MinMaxNormalize(m)
+	for j := 0; j < 3; j++ {
+		col := normalized.ColView(j)
+		if got := mat.Min(col); got != 0 {
+			t.Errorf("column %d min %v, want 0", j, got)
+		}
+		if got := mat.Max(col); got != 1 {
+			t.Errorf("column %d max %v, want 1", j, got)
+		}
+	}
+	if again := ApplyMinMax(m, min, max); !mat.Equal(again, normalized) {
+		t.Error("ApplyMinMax with the stored parameters differs from MinMaxNormalize")
+	}
+}
+
+func TestStandardize(t *testing.T) {
+	m := featureData()
+	out, mean, std := Standardize(m)
+	for j := 0; j < 3; j++ {
+		colMean, colVar := weightStats(mat.DenseCopyOf(out.ColView(j)))
+		if math.Abs(colMean) > 1e-12 {
+			t.Errorf("column %d mean %v, want 0", j, colMean)
+		}
+		if math.Abs(colVar-1) > 1e-12 {
+			t.Errorf("column %d variance %v, want 1", j, colVar)
+		}
+	}
+	if again := ApplyStandardize(m, mean, std); !mat.Equal(again, out) {
+		t.Error("ApplyStandardize with the stored parameters differs from Standardize")
+	}
+
+	constant := mat.NewDense(3, 1, []float64{5, 5, 5})
+	out, _, std = Standardize(constant)
+	if std[0] != 1 || mat.Max(out) != 0 || mat.Min(out) != 0 {
+		t.Errorf("constant column standardized to %v with std %v, want zeros with std 1", out.RawMatrix().Data, std[0])
+	}
+}
+
+func TestTrainTestSplit(t *testing.T) {
+	inputs := mat.NewDense(100, 2, nil)
+	targets := mat.NewDense(100, 1, nil)
+	for i := 0; i < 100; i++ {
+		inputs.SetRow(i, []float64{float64(i), -float64(i)})
+		targets.Set(i, 0, float64(i))
+	}
+	trainIn, trainTgt, testIn, testTgt := TrainTestSplit(inputs, targets, 0.2, 1)
+	if r, _ := trainIn.Dims(); r != 80 {
+		t.Errorf("training set has %d rows, want 80", r)
+	}
+	if r, _ := testIn.Dims(); r != 20 {
+		t.Errorf("test set has %d rows, want 20", r)
+	}
+
+	seen := make(map[float64]bool)
+	for _, set := range []struct{ in, tgt *mat.Dense }{{trainIn, trainTgt}, {testIn, testTgt}} {
+		r, _ := set.in.Dims()
+		for i := 0; i < r; i++ {
+			id := set.in.At(i, 0)
+			if set.in.At(i, 1) != -id || set.tgt.At(i, 0) != id {
+				t.Errorf("row %v lost its pairing", id)
+			}
+			if seen[id] {
+				t.Errorf("row %v appears in both sets", id)
+			}
+			seen[id] = true
+		}
+	}
+	if len(seen) != 100 {
+		t.Errorf("split kept %d of 100 rows", len(seen))
+	}
+
+	assertPanics(t, "TrainTestSplit with fraction 0", func() { TrainTestSplit(inputs, targets, 0, 1) })
+	assertPanics(t, "TrainTestSplit with fraction 1", func() { TrainTestSplit(inputs, targets, 1, 1) })
+	assertPanics(t, "TrainTestSplit with no test rows", func() { TrainTestSplit(inputs, targets, 0.001, 1) })
+	assertPanics(t, "TrainTestSplit with no training rows", func() { TrainTestSplit(inputs, targets, 0.999, 1) })
+}
+
+func TestLoadCSV(t *testing.T) {
+	const data = "1, 2, 3, 0\n4, 5, 6, 1\n7.5, -8, 9, 0\n"
+	inputs, targets, err := LoadCSV(strings.NewReader(data), []int{2, 0}, []int{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIn := mat.NewDense(3, 2, []float64{3, 1, 6, 4, 9, 7.5})
+	wantTgt := mat.NewDense(3, 1, []float64{0, 1, 0})
+	if !mat.Equal(inputs, wantIn) {
+		t.Errorf("inputs %v, want %v", inputs.RawMatrix().Data, wantIn.RawMatrix().Data)
+	}
+	if !mat.Equal(targets, wantTgt) {
+		t.Errorf("targets %v, want %v", targets.RawMatrix().Data, wantTgt.RawMatrix().Data)
+	}
+
+	for name, bad := range map[string]string{
+		"non-numeric cell": "1,2\n3,x\n",
+		"ragged row":       "1,2\n3\n",
+	} {
+		if _, _, err := LoadCSV(strings.NewReader(bad), []int{0}, []int{1}); err == nil {
+			t.Errorf("%s gave no error", name)
+		}
+	}
+	for _, cols := range [][2][]int{{nil, {3}}, {{0}, {}}} {
+		if _, _, err := LoadCSV(strings.NewReader(data), cols[0], cols[1]); err == nil {
+			t.Errorf("feature columns %v and target columns %v gave no error", cols[0], cols[1])
+		}
+	}
+}
+
+func TestOneHotRoundTrip(t *testing.T) {
+	labels := []int{2, 0, 1, 1, 3}
+	oneHot := OneHot(labels, 4)
+	if r, c := oneHot.Dims(); r != 5 || c != 4 {
+		t.Fatalf("one-hot matrix is %dx%d, want 5x4", r, c)
+	}
+	if got := mat.Sum(oneHot); got != 5 {
+		t.Errorf("one-hot matrix sums to %v, want one 1 per row", got)
+	}
+	for i, got := range ArgmaxRows(oneHot) {
+		if got != labels[i] {
+			t.Errorf("row %d: label %d, want %d", i, got, labels[i])
+		}
+	}
+	assertPanics(t, "OneHot with label 4 of 4 classes", func() { OneHot([]int{4}, 4) })
+	assertPanics(t, "OneHot with label -1", func() { OneHot([]int{-1}, 4) })
+}
+
+func TestKFoldCrossValidate(t *testing.T) {
+	for _, tc := range []struct {
+		rows      int
+		foldSizes []int
+	}{
+		{100, []int{20, 20, 20, 20, 20}},
+		{103, []int{21, 21, 21, 20, 20}},
+	} {
+		inputs, targets := randomDense(tc.rows, 2, 1), randomDense(tc.rows, 1, 2)
+		var trainSizes []int
+		scores := KFoldCrossValidate(inputs, targets, 5,
+			func(trIn, trTgt *mat.Dense) *NeuralNetwork {
+				r, _ := trIn.Dims()
+				trainSizes = append(trainSizes, r)
+				return NewNeuralNetworkWithSeed([]int{2, 1}, 1)
+			},
+			func(nn *NeuralNetwork, teIn, teTgt *mat.Dense) float64 {
+				r, _ := teIn.Dims()
+				return float64(r)
+			})
+		if len(scores) != 5 {
+			t.Fatalf("%d rows: got %d scores, want 5", tc.rows, len(scores))
+		}
+		for i, size := range tc.foldSizes {
+			if int(scores[i]) != size || trainSizes[i] != tc.rows-size {
+				t.Errorf("%d rows, fold %d: tested on %v and trained on %d rows, want %d and %d",
+					tc.rows, i, scores[i], trainSizes[i], size, tc.rows-size)
+			}
+		}
+	}
+}
+
+func TestSmoothLabels(t *testing.T) {
+	binary := SmoothLabels(mat.NewDense(2, 1, []float64{1, 0}), 0.1)
+	if got := binary.At(0, 0); math.Abs(got-0.95) > 1e-15 {
+		t.Errorf("smoothed 1 is %v, want 0.95", got)
+	}
+	if got := binary.At(1, 0); math.Abs(got-0.05) > 1e-15 {
+		t.Errorf("smoothed 0 is %v, want 0.05", got)
+	}
+	oneHot := SmoothLabels(OneHot([]int{1}, 2), 0.1)
+	want := mat.NewDense(1, 2, []float64{0.05, 0.95})
+	if !mat.EqualApprox(oneHot, want, 1e-15) {
+		t.Errorf("smoothed one-hot row %v, want %v", oneHot.RawMatrix().Data, want.RawMatrix().Data)
+	}
+	assertPanics(t, "SmoothLabels with epsilon 1", func() { SmoothLabels(oneHot, 1) })
+}
+
+func TestValidateInputs(t *testing.T) {
+	inputs, targets := xorData()
+	if err := ValidateInputs(inputs, targets); err != nil {
+		t.Errorf("clean data: %v", err)
+	}
+	inputs.Set(2, 1, math.NaN())
+	err := ValidateInputs(inputs, targets)
+	if want := "nngo: input row 2 column 1 is NaN"; err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+
+	nn := New([]int{2, 2, 1}, WithSeed(1), WithInputValidation())
+	if _, err := nn.Train(inputs, targets, 1, 0.5); err == nil {
+		t.Error("training with input validation accepted a NaN input")
+	}
+}
+
+func TestNormalizeColumns(t *testing.T) {
+	m := featureData()
+	full, _, _ := MinMaxNormalize(m)
+	normalized, min, max := MinMaxNormalizeColumns(m, []int{1})
+	if !mat.Equal(normalized.ColView(1), full.ColView(1)) {
+		t.Error("listed column 1 not min-max normalized")
+	}
+	for _, j := range []int{0, 2} {
+		if !mat.Equal(normalized.ColView(j), m.ColView(j)) {
+			t.Errorf("unlisted column %d changed", j)
+		}
+		if min[j] != 0 || max[j] != 1 {
+			t.Errorf("unlisted column %d got min %v max %v, want 0 and 1", j, min[j], max[j])
+		}
+	}
+
+	fullStd, _, _ := Standardize(m)
+	out, mean, std := StandardizeColumns(m, []int{0, 2})
+	for _, j := range []int{0, 2} {
+		if !mat.Equal(out.ColView(j), fullStd.ColView(j)) {
+			t.Errorf("listed column %d not standardized", j)
+		}
+	}
+	if !mat.Equal(out.ColView(1), m.ColView(1)) || mean[1] != 0 || std[1] != 1 {
+		t.Errorf("unlisted column 1 changed or got mean %v std %v, want 0 and 1", mean[1], std[1])
+	}
+
+	assertPanics(t, "MinMaxNormalizeColumns with column 3", func() { MinMaxNormalizeColumns(m, []int{3}) })
+	assertPanics(t, "StandardizeColumns with column -1", func() { StandardizeColumns(m, []int{-1}) })
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Ensemble combines independently trained models by averaging their
+// outputs, which usually beats each member on its own
+type Ensemble []Predictor
+
+var _ Predictor = Ensemble(nil)
+
+// Predict returns the element-wise mean of the members' outputs: the
+// averaged prediction for regression, or averaged class probabilities for
+// classification. It panics if the ensemble is empty or the members'
+// outputs differ in shape.
+func (e Ensemble) Predict(inputs *mat.Dense) *mat.Dense {
+	if len(e) == 0 {
+		panic("nngo: empty ensemble")
+	}
+	mean := mat.DenseCopyOf(e[0].Predict(inputs))
+	for i, member := range e[1:] {
+		outputs := member.Predict(inputs)
+		if err := sameShape(outputs, mean); err != nil {
+			panic(fmt.Sprintf("nngo: ensemble member %d output %v", i+1, err))
+		}
+		mean.Add(mean, outputs)
+	}
+	mean.Scale(1/float64(len(e)), mean)
+	return mean
+}
+
+// CheckDims reports the first member whose CheckDims rejects inputs and
+// targets, so that PredictHandler can reject mismatched requests for an
+// ensemble as it does for a network. Members without a CheckDims method
+// are not checked, and an empty ensemble is an error.
+func (e Ensemble) CheckDims(inputs, targets *mat.Dense) error {
+	if len(e) == 0 {
+		return errors.New("nngo: empty ensemble")
+	}
+	for i, member := range e {
+		if checker, ok := member.(dimsChecker); ok {
+			if err := checker.CheckDims(inputs, targets); err != nil {
+				return fmt.Errorf("ensemble member %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PredictClasses returns the class of each sample as the argmax of the
+// averaged probabilities from Predict
+func (e Ensemble) PredictClasses(inputs *mat.Dense) []int {
+	return ArgmaxRows(e.Predict(inputs))
+}
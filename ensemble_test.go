@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEnsembleAccuracy(t *testing.T) {
+	inputs, targets := xorData()
+	var ensemble Ensemble
+	best := 0.0
+	for seed := int64(1); seed <= 3; seed++ {
+		nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, seed)
+		if _, err := nn.Train(inputs, targets, 3000, 0.5); err != nil {
+			t.Fatal(err)
+		}
+		best = max(best, Accuracy(nn.Predict(inputs), targets, 0.5))
+		ensemble = append(ensemble, nn)
+	}
+	if got := Accuracy(ensemble.Predict(inputs), targets, 0.5); got < best {
+		t.Errorf("ensemble accuracy %v, best member %v", got, best)
+	}
+	assertPanics(t, "Predict on an empty ensemble", func() { Ensemble{}.Predict(inputs) })
+}
+
+func TestEnsembleHandlerRejectsDims(t *testing.T) {
+	ensemble := Ensemble{NewNeuralNetworkWithSeed([]int{2, 1}, 1), constPredictor{0}}
+	code, body := post(PredictHandler(ensemble), "/predict", `{"inputs": [[0, 1, 2]]}`)
+	want := "{\"error\":\"ensemble member 0: nngo: input has 3 features, network expects 2\"}\n"
+	if code != http.StatusBadRequest || body != want {
+		t.Errorf("status %d, body %q; want 400 and %q", code, body, want)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import "gonum.org/v1/gonum/mat"
+
+// Task tells Evaluate which metric suits the network's predictions
+type Task int
+
+const (
+	// Regression scores predictions with R2Score
+	Regression Task = iota
+	// Classification scores predictions by accuracy
+	Classification
+)
+
+// String implements fmt.Stringer
+func (t Task) String() string {
+	switch t {
+	case Regression:
+		return "regression"
+	case Classification:
+		return "classification"
+	}
+	return "unknown"
+}
+
+// defaultLearningRate is the learning rate Fit uses unless
+// WithLearningRate sets another
+const defaultLearningRate = 0.1
+
+// Fit trains the network on X and y for the given number of epochs with
+// the learning rate set by WithLearningRate, 0.1 by default. It is Train
+// for callers used to scikit-learn, discarding the loss history.
+func (nn *NeuralNetwork) Fit(X, y *mat.Dense, epochs int) error {
+	learningRate := nn.learningRate
+	if learningRate == 0 {
+		learningRate = defaultLearningRate
+	}
+	_, err := nn.Train(X, y, epochs, learningRate)
+	return err
+}
+
+// PartialFit continues training from the network's current state for the
+// given number of epochs, as when new batches of data arrive for a model
+// that has already been trained. Nothing is reinitialized: the weights, the
+// optimizer's accumulated state such as momentum or Adam's moment
+// estimates, the batch normalization running statistics and any fitted
+// target normalization all carry over from earlier calls, so successive
+// calls train exactly as one longer run would on the same data. Epoch
+// numbers continue too: the progress callback and checkpoints of a second
+// call of 10 epochs after a first one see epochs 10 to 19. Call Reset
+// first to start over instead. It returns the loss history like Train.
+func (nn *NeuralNetwork) PartialFit(inputs, targets *mat.Dense, epochs int, learningRate float64) ([]float64, error) {
+	nn.epochOffset = nn.partialEpochs
+	history, err := nn.Train(inputs, targets, epochs, learningRate)
+	nn.epochOffset = 0
+	nn.partialEpochs += len(history)
+	return history, err
+}
+
+// Evaluate predicts X and returns the network's loss against y together
+// with the metric of its task, see WithTask: R2Score for Regression, and
+// for Classification the accuracy, taken as the fraction of matching
+// argmax classes for multi-output networks, of matching classes for
+// SparseCategoricalCrossEntropy labels and of outputs on the right side of
+// 0.5 otherwise. Both are measured in the units of the training targets,
+// after any target normalization is undone but before any output
+// transform. Like Predict, it panics if X does not match the input layer.
+func (nn *NeuralNetwork) Evaluate(X, y *mat.Dense) (loss float64, metric float64) {
+	predictions := nn.predict(X)
+	loss = nn.loss.Loss(predictions, y)
+	if nn.task != Classification {
+		return loss, R2Score(predictions, y)
+	}
+	if _, ok := nn.loss.(SparseCategoricalCrossEntropy); ok {
+		return loss, sparseAccuracy(predictions, y)
+	}
+	if _, outputs := predictions.Dims(); outputs > 1 {
+		return loss, AccuracyArgmax(predictions, y)
+	}
+	return loss, Accuracy(predictions, y, 0.5)
+}
+
+// sparseAccuracy returns the fraction of samples whose argmax prediction
+// is the integer class label in the single column of labels
+func sparseAccuracy(predictions, labels *mat.Dense) float64 {
+	predicted := ArgmaxRows(predictions)
+	correct := 0
+	for i, class := range predicted {
+		if float64(class) == labels.At(i, 0) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(predicted))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFitEvaluateXOR(t *testing.T) {
+	X, y := xorData()
+	nn := New([]int{2, 4, 1}, WithSeed(1), WithTask(Classification), WithLearningRate(0.5))
+	if err := nn.Fit(X, y, 10000); err != nil {
+		t.Fatal(err)
+	}
+	loss, accuracy := nn.Evaluate(X, y)
+	if accuracy != 1 {
+		t.Errorf("XOR accuracy %v, want 1", accuracy)
+	}
+	if loss > 0.05 {
+		t.Errorf("XOR loss %v, want below 0.05", loss)
+	}
+
+	regression := New([]int{2, 4, 1}, WithSeed(1), WithLearningRate(0.5))
+	if err := regression.Fit(X, y, 10000); err != nil {
+		t.Fatal(err)
+	}
+	if _, r2 := regression.Evaluate(X, y); r2 < 0.8 {
+		t.Errorf("regression R2 %v, want at least 0.8", r2)
+	}
+	if err := nn.Fit(X, mat.NewDense(4, 2, nil), 1); err == nil {
+		t.Error("Fit accepted targets with the wrong number of outputs")
+	}
+}
+
+func TestPartialFitWarmStart(t *testing.T) {
+	X, y := xorData()
+	newNet := func() *NeuralNetwork {
+		return New([]int{2, 4, 1}, WithSeed(1), WithOptimizer(NewAdam()))
+	}
+
+	single := newNet()
+	singleHistory, err := single.PartialFit(X, y, 200, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warm := newNet()
+	if _, err := warm.PartialFit(X, y, 200, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	history, err := warm.PartialFit(X, y, 200, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, last := singleHistory[len(singleHistory)-1], history[len(history)-1]; last >= first {
+		t.Errorf("loss %v after two PartialFit calls, want below %v after one", last, first)
+	}
+
+	long := newNet()
+	if _, err := long.PartialFit(X, y, 400, 0.01); err != nil {
+		t.Fatal(err)
+	}
+	longWeights := long.Weights()
+	for l, w := range warm.Weights() {
+		if !mat.Equal(w, longWeights[l]) {
+			t.Errorf("layer %d weights after two PartialFit calls differ from one run of the same total epochs", l)
+		}
+	}
+}
+
+func TestPartialFitContinuesEpochs(t *testing.T) {
+	X, y := xorData()
+	var epochs []int
+	nn := New([]int{2, 3, 1}, WithSeed(1), WithProgress(func(epoch int, _ float64) { epochs = append(epochs, epoch) }))
+	for call := 0; call < 2; call++ {
+		if _, err := nn.PartialFit(X, y, 3, 0.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := nn.Train(X, y, 1, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	nn.Reset(1)
+	if _, err := nn.PartialFit(X, y, 1, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{0, 1, 2, 3, 4, 5, 0, 0}
+	if !slices.Equal(epochs, want) {
+		t.Errorf("progress epochs %v, want %v", epochs, want)
+	}
+}
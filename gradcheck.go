@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GradientCheck compares the backpropagated gradients of the loss over
+// inputs and targets with central-difference estimates
+// (loss(w+epsilon) - loss(w-epsilon)) / (2*epsilon) for every weight and
+// bias, and returns their relative difference
+// ||numeric - analytic|| / (||numeric|| + ||analytic||). Values below
+// about 1e-5 indicate correct gradients; 1e-4 is a reasonable epsilon.
+// The network runs as in Gradients and is left unchanged. It panics if
+// the dimensions do not match the network, see CheckDims.
+func (nn *NeuralNetwork) GradientCheck(inputs, targets *mat.Dense, epsilon float64) float64 {
+	weightGradients, biasGradients, err := nn.Gradients(inputs, targets)
+	if err != nil {
+		panic(err)
+	}
+	targets = nn.learnedTargets(targets, false)
+
+	loss := func() float64 {
+		pass := nn.feedforward(inputs, evalMode)
+		return nn.loss.Loss(pass.outputs[len(pass.outputs)-1], targets)
+	}
+
+	var diffSquares, numericSquares, analyticSquares float64
+	check := func(params, analytic *mat.Dense) {
+		r, c := params.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				original := params.At(i, j)
+				params.Set(i, j, original+epsilon)
+				plus := loss()
+				params.Set(i, j, original-epsilon)
+				minus := loss()
+				params.Set(i, j, original)
+
+				numeric := (plus - minus) / (2 * epsilon)
+				d := numeric - analytic.At(i, j)
+				diffSquares += d * d
+				numericSquares += numeric * numeric
+				analyticSquares += analytic.At(i, j) * analytic.At(i, j)
+			}
+		}
+	}
+	for l := range nn.weights {
+		check(nn.weights[l], weightGradients[l])
+		check(nn.biases[l], biasGradients[l])
+	}
+
+	denominator := math.Sqrt(numericSquares) + math.Sqrt(analyticSquares)
+	if denominator == 0 {
+		return 0
+	}
+	return math.Sqrt(diffSquares) / denominator
+}
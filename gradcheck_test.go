@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGradientCheckSigmoid(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithInit([]int{2, 3, 1}, GlorotUniform, 1)
+	if diff := nn.GradientCheck(inputs, targets, 1e-4); diff >= 1e-6 {
+		t.Errorf("sigmoid network gradient relative error %v, want below 1e-6", diff)
+	}
+}
+
+func TestGradientCheckLeavesNormalizationUnfitted(t *testing.T) {
+	inputs := randomDense(20, 3, 1)
+	targets := randomDense(20, 2, 2)
+	targets.Scale(100, targets)
+	nn := New([]int{3, 4, 2}, WithSeed(1), WithOutputActivation(Linear), WithTargetNormalization())
+	before := nn.Predict(inputs)
+	if diff := nn.GradientCheck(inputs, targets, 1e-4); diff >= 1e-6 {
+		t.Errorf("normalized network gradient relative error %v, want below 1e-6", diff)
+	}
+	if after := nn.Predict(inputs); !mat.Equal(before, after) {
+		t.Error("GradientCheck changed Predict")
+	}
+}
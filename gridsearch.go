@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GridSearch scores every combination of the parameter values in grid,
+// one value per parameter name, by calling buildAndScore with that
+// combination, and returns the highest-scoring combination and its score.
+// Combinations are evaluated concurrently on runtime.NumCPU() workers, so
+// buildAndScore must be safe for concurrent use. GridSearch does not read
+// inputs and targets itself; buildAndScore typically builds, trains and
+// scores its own network on them. Ties go to the combination
+// that comes first when names are sorted and values kept in grid order. A
+// parameter without values leaves nothing to search, returning nil and
+// -Inf.
+func GridSearch(inputs, targets *mat.Dense, grid map[string][]float64, buildAndScore func(params map[string]float64) float64) (bestParams map[string]float64, bestScore float64) {
+	names := make([]string, 0, len(grid))
+	for name := range grid {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	combinations := []map[string]float64{{}}
+	for _, name := range names {
+		var next []map[string]float64
+		for _, combination := range combinations {
+			for _, v := range grid[name] {
+				params := make(map[string]float64, len(names))
+				for k, existing := range combination {
+					params[k] = existing
+				}
+				params[name] = v
+				next = append(next, params)
+			}
+		}
+		combinations = next
+	}
+
+	scores := make([]float64, len(combinations))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), len(combinations)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// Each call gets its own copy to modify freely
+				params := make(map[string]float64, len(combinations[i]))
+				for k, v := range combinations[i] {
+					params[k] = v
+				}
+				scores[i] = buildAndScore(params)
+			}
+		}()
+	}
+	for i := range combinations {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	bestScore = math.Inf(-1)
+	for i, score := range scores {
+		if bestParams == nil || score > bestScore {
+			bestParams, bestScore = combinations[i], score
+		}
+	}
+	return bestParams, bestScore
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGridSearch(t *testing.T) {
+	inputs, targets := xorData()
+	grid := map[string][]float64{
+		"lr":     {0.1, 0.5},
+		"hidden": {2, 4},
+	}
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	best, score := GridSearch(inputs, targets, grid, func(params map[string]float64) float64 {
+		mu.Lock()
+		seen[fmt.Sprint(params["lr"], params["hidden"])] = true
+		mu.Unlock()
+		// Peaks at lr 0.5 with 2 hidden units
+		return params["lr"] - params["hidden"]
+	})
+	if len(seen) != 4 {
+		t.Errorf("explored %d combinations, want 4: %v", len(seen), seen)
+	}
+	if best["lr"] != 0.5 || best["hidden"] != 2 || score != -1.5 {
+		t.Errorf("best %v with score %v, want lr 0.5 and 2 hidden with -1.5", best, score)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "gonum.org/v1/gonum/mat"
+
+// PermutationImportance scores each input feature by how much worse the
+// network does without it: the increase of metric, an error measure such as
+// MSE{}.Loss where higher is worse, when that feature's column is shuffled
+// across samples, breaking its link with the targets. Features the network
+// ignores score near 0. Predictions are scored before any output
+// transform set by WithOutputTransform. The shuffles use the network's
+// random generator, so seeded networks give repeatable scores. It panics
+// if the dimensions do not match the network, see CheckDims.
+func (nn *NeuralNetwork) PermutationImportance(inputs, targets *mat.Dense, metric func(pred, tgt *mat.Dense) float64) []float64 {
+	if err := nn.CheckDims(inputs, targets); err != nil {
+		panic(err)
+	}
+	baseline := metric(nn.predict(inputs), targets)
+
+	rows, cols := inputs.Dims()
+	permuted := mat.DenseCopyOf(inputs)
+	importances := make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		for i, src := range permutation(rows, nn.rng) {
+			permuted.Set(i, j, inputs.At(src, j))
+		}
+		importances[j] = metric(nn.predict(permuted), targets) - baseline
+		for i := 0; i < rows; i++ {
+			permuted.Set(i, j, inputs.At(i, j))
+		}
+	}
+	return importances
+}
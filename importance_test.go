@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPermutationImportanceNoise(t *testing.T) {
+	// The target is the first feature; the second is pure noise
+	inputs := randomDense(200, 2, 1)
+	targets := mat.NewDense(200, 1, nil)
+	for i := 0; i < 200; i++ {
+		targets.Set(i, 0, inputs.At(i, 0))
+	}
+	nn := New([]int{2, 1}, WithSeed(1), WithOutputActivation(Linear))
+	if _, err := nn.Train(inputs, targets, 3000, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	importances := nn.PermutationImportance(inputs, targets, MSE{}.Loss)
+	if importances[0] < 0.05 {
+		t.Errorf("signal feature importance %v, want a clear increase in error", importances[0])
+	}
+	if math.Abs(importances[1]) > 1e-3 {
+		t.Errorf("noise feature importance %v, want about 0", importances[1])
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// InitStrategy selects how a network's initial weights are drawn
+type InitStrategy int
+
+const (
+	// UniformInit draws every weight uniformly from [0, 1)
+	UniformInit InitStrategy = iota
+	// GlorotUniform draws weights uniformly from [-limit, limit] with
+	// limit = sqrt(6 / (fanIn + fanOut)), keeping activation variance
+	// roughly constant across sigmoid and tanh layers
+	GlorotUniform
+	// HeNormal draws weights from a normal distribution with mean 0 and
+	// standard deviation sqrt(2 / fanIn), which suits ReLU layers
+	HeNormal
+)
+
+// String implements fmt.Stringer
+func (s InitStrategy) String() string {
+	switch s {
+	case UniformInit:
+		return "uniform"
+	case GlorotUniform:
+		return "glorot-uniform"
+	case HeNormal:
+		return "he-normal"
+	}
+	return fmt.Sprintf("InitStrategy(%d)", int(s))
+}
+
+// fill overwrites the fanOut x fanIn weight matrix w with values drawn
+// from rng according to s
+func (s InitStrategy) fill(w *mat.Dense, rng *rand.Rand) {
+	fanOut, fanIn := w.Dims()
+
+	var sample func() float64
+	switch s {
+	case UniformInit:
+		sample = rng.Float64
+	case GlorotUniform:
+		limit := math.Sqrt(6.0 / float64(fanIn+fanOut))
+		sample = func() float64 { return (2*rng.Float64() - 1) * limit }
+	case HeNormal:
+		std := math.Sqrt(2.0 / float64(fanIn))
+		sample = func() float64 { return rng.NormFloat64() * std }
+	default:
+		panic(fmt.Sprintf("nngo: unknown init strategy %v", s))
+	}
+
+	for i := 0; i < fanOut; i++ {
+		for j := 0; j < fanIn; j++ {
+			w.Set(i, j, sample())
+		}
+	}
+}
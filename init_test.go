@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSeedGivesIdenticalWeights(t *testing.T) {
+	a := NewNeuralNetworkWithSeed([]int{3, 5, 2}, 42)
+	b := NewNeuralNetworkWithSeed([]int{3, 5, 2}, 42)
+	c := NewNeuralNetworkWithSeed([]int{3, 5, 2}, 43)
+	for l := range a.Weights() {
+		if !mat.Equal(a.Weights()[l], b.Weights()[l]) {
+			t.Errorf("layer %d weights differ for equal seeds", l)
+		}
+	}
+	if mat.Equal(a.Weights()[0], c.Weights()[0]) {
+		t.Error("different seeds gave identical weights")
+	}
+}
+
+// weightStats returns the mean and variance of the entries of w
+func weightStats(w *mat.Dense) (mean, variance float64) {
+	data := mat.DenseCopyOf(w).RawMatrix().Data
+	for _, v := range data {
+		mean += v
+	}
+	mean /= float64(len(data))
+	for _, v := range data {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(data))
+}
+
+func TestGlorotUniformVariance(t *testing.T) {
+	nn := NewNeuralNetworkWithInit([]int{200, 300, 1}, GlorotUniform, 1)
+	w := nn.Weights()[0]
+	limit := math.Sqrt(6.0 / (200 + 300))
+	if got := mat.Max(w); got > limit {
+		t.Errorf("max weight %v exceeds limit %v", got, limit)
+	}
+	if got := mat.Min(w); got < -limit {
+		t.Errorf("min weight %v below -limit %v", got, -limit)
+	}
+	// Uniform on [-limit, limit] has variance limit²/3 = 2/(fanIn+fanOut)
+	want := limit * limit / 3
+	if _, got := weightStats(w); math.Abs(got-want) > 0.05*want {
+		t.Errorf("weight variance %v, want %v", got, want)
+	}
+}
+
+func TestHeNormalStd(t *testing.T) {
+	nn := NewNeuralNetworkWithInit([]int{100, 400, 1}, HeNormal, 1)
+	want := math.Sqrt(2.0 / 100)
+	mean, variance := weightStats(nn.Weights()[0])
+	if math.Abs(mean) > 0.05*want {
+		t.Errorf("weight mean %v, want about 0", mean)
+	}
+	if got := math.Sqrt(variance); math.Abs(got-want) > 0.05*want {
+		t.Errorf("weight std %v, want %v", got, want)
+	}
+}
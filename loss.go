@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Loss measures how far predictions are from their targets. Gradient
+// returns the derivative of Loss with respect to each prediction and is
+// what Train backpropagates through the output activation.
+type Loss interface {
+	Loss(pred, target *mat.Dense) float64
+	Gradient(pred, target *mat.Dense) *mat.Dense
+}
+
+// MSE is the mean squared error over all output elements
+type MSE struct{}
+
+// Loss implements Loss
+func (MSE) Loss(pred, target *mat.Dense) float64 {
+	diff := new(mat.Dense)
+	diff.Sub(pred, target)
+	return meanSquare(diff)
+}
+
+// Gradient implements Loss
+func (MSE) Gradient(pred, target *mat.Dense) *mat.Dense {
+	r, c := pred.Dims()
+	grad := new(mat.Dense)
+	grad.Sub(pred, target)
+	grad.Scale(2/float64(r*c), grad)
+	return grad
+}
+
+// MAE is the mean absolute error over all output elements. Its gradient
+// is sign(pred - target) / elements, taken as 0 where pred equals target.
+type MAE struct{}
+
+// Loss implements Loss
+func (MAE) Loss(pred, target *mat.Dense) float64 {
+	r, c := pred.Dims()
+	sum := 0.0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			sum += math.Abs(pred.At(i, j) - target.At(i, j))
+		}
+	}
+	return sum / float64(r*c)
+}
+
+// Gradient implements Loss
+func (MAE) Gradient(pred, target *mat.Dense) *mat.Dense {
+	r, c := pred.Dims()
+	n := float64(r * c)
+	grad := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			switch res := pred.At(i, j) - target.At(i, j); {
+			case res > 0:
+				grad.Set(i, j, 1/n)
+			case res < 0:
+				grad.Set(i, j, -1/n)
+			}
+		}
+	}
+	return grad
+}
+
+// Huber is the Huber loss averaged over all output elements: r²/2 for a
+// residual r = pred - target with |r| <= Delta, and Delta*(|r| - Delta/2)
+// beyond, so it is half the squared error for small residuals and grows
+// only linearly for outliers. Each element's gradient is r clamped to
+// [-Delta, Delta], divided by the number of elements.
+type Huber struct {
+	Delta float64
+}
+
+// Loss implements Loss
+func (h Huber) Loss(pred, target *mat.Dense) float64 {
+	r, c := pred.Dims()
+	sum := 0.0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			res := math.Abs(pred.At(i, j) - target.At(i, j))
+			if res <= h.Delta {
+				sum += res * res / 2
+			} else {
+				sum += h.Delta * (res - h.Delta/2)
+			}
+		}
+	}
+	return sum / float64(r*c)
+}
+
+// Gradient implements Loss
+func (h Huber) Gradient(pred, target *mat.Dense) *mat.Dense {
+	r, c := pred.Dims()
+	n := float64(r * c)
+	grad := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			res := pred.At(i, j) - target.At(i, j)
+			grad.Set(i, j, math.Max(-h.Delta, math.Min(h.Delta, res))/n)
+		}
+	}
+	return grad
+}
+
+// probabilityEpsilon bounds the predictions the cross-entropy losses take
+// logarithms of and divide by away from 0 and 1
+const probabilityEpsilon = 1e-7
+
+// clipProbability clamps p to [probabilityEpsilon, 1-probabilityEpsilon],
+// so a confident wrong prediction gives a large but finite loss
+func clipProbability(p float64) float64 {
+	return math.Min(math.Max(p, probabilityEpsilon), 1-probabilityEpsilon)
+}
+
+// CrossEntropy is the binary cross-entropy averaged over all output
+// elements. Predictions should lie in [0, 1], e.g. from a sigmoid output
+// layer, and are clipped to [1e-7, 1 - 1e-7] first. Each target is the
+// probability of the positive class.
+type CrossEntropy struct{}
+
+// Loss implements Loss
+func (CrossEntropy) Loss(pred, target *mat.Dense) float64 {
+	r, c := pred.Dims()
+	sum := 0.0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			p, t := clipProbability(pred.At(i, j)), target.At(i, j)
+			sum -= t*math.Log(p) + (1-t)*math.Log(1-p)
+		}
+	}
+	return sum / float64(r*c)
+}
+
+// Gradient implements Loss
+func (CrossEntropy) Gradient(pred, target *mat.Dense) *mat.Dense {
+	r, c := pred.Dims()
+	n := float64(r * c)
+	grad := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			p, t := clipProbability(pred.At(i, j)), target.At(i, j)
+			grad.Set(i, j, (p-t)/(p*(1-p))/n)
+		}
+	}
+	return grad
+}
+
+// CategoricalCrossEntropy is the multi-class cross-entropy averaged over
+// samples. Each prediction row must be a probability distribution and each
+// target row a one-hot (or soft) class distribution. Predictions are
+// clipped like those of CrossEntropy. Pair it with a SoftmaxOutput layer.
+type CategoricalCrossEntropy struct{}
+
+// Loss implements Loss
+func (CategoricalCrossEntropy) Loss(pred, target *mat.Dense) float64 {
+	r, c := pred.Dims()
+	sum := 0.0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if t := target.At(i, j); t != 0 {
+				sum -= t * math.Log(clipProbability(pred.At(i, j)))
+			}
+		}
+	}
+	return sum / float64(r)
+}
+
+// Gradient implements Loss
+func (CategoricalCrossEntropy) Gradient(pred, target *mat.Dense) *mat.Dense {
+	r, c := pred.Dims()
+	grad := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			grad.Set(i, j, -target.At(i, j)/clipProbability(pred.At(i, j))/float64(r))
+		}
+	}
+	return grad
+}
+
+// SparseCategoricalCrossEntropy is CategoricalCrossEntropy with each
+// target row holding the sample's class index instead of a one-hot row, so
+// targets are a single column however many classes there are. Pair it
+// with a SoftmaxOutput layer. Using a label outside the prediction's
+// columns panics.
+type SparseCategoricalCrossEntropy struct{}
+
+// Loss implements Loss
+func (SparseCategoricalCrossEntropy) Loss(pred, target *mat.Dense) float64 {
+	r, _ := pred.Dims()
+	sum := 0.0
+	for i := 0; i < r; i++ {
+		sum -= math.Log(clipProbability(pred.At(i, classLabel(pred, target, i))))
+	}
+	return sum / float64(r)
+}
+
+// Gradient implements Loss
+func (SparseCategoricalCrossEntropy) Gradient(pred, target *mat.Dense) *mat.Dense {
+	r, c := pred.Dims()
+	grad := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		j := classLabel(pred, target, i)
+		grad.Set(i, j, -1/clipProbability(pred.At(i, j))/float64(r))
+	}
+	return grad
+}
+
+// classLabel returns the class index in row i of the label column target,
+// panicking unless it is a whole number indexing a column of pred
+func classLabel(pred, target *mat.Dense, i int) int {
+	_, c := pred.Dims()
+	v := target.At(i, 0)
+	label := int(v)
+	if float64(label) != v || label < 0 || label >= c {
+		panic(fmt.Sprintf("nngo: label %v at row %d is not a class in [0, %d)", v, i, c))
+	}
+	return label
+}
+
+// softmaxCrossEntropyDelta is the gradient of CategoricalCrossEntropy with
+// respect to the inputs of a softmax layer, (pred - target) / samples
+func softmaxCrossEntropyDelta(pred, target *mat.Dense) *mat.Dense {
+	r, _ := pred.Dims()
+	delta := new(mat.Dense)
+	delta.Sub(pred, target)
+	delta.Scale(1/float64(r), delta)
+	return delta
+}
+
+// softmaxSparseCrossEntropyDelta is softmaxCrossEntropyDelta for a column
+// of class labels, (pred - onehot(target)) / samples, with the one-hot rows
+// smoothed as by SmoothLabels with epsilon
+func softmaxSparseCrossEntropyDelta(pred, target *mat.Dense, epsilon float64) *mat.Dense {
+	r, c := pred.Dims()
+	delta := mat.DenseCopyOf(pred)
+	if epsilon > 0 {
+		off := epsilon / float64(max(c, 2))
+		delta.Apply(func(_, _ int, p float64) float64 { return p - off }, delta)
+	}
+	for i := 0; i < r; i++ {
+		j := classLabel(pred, target, i)
+		delta.Set(i, j, delta.At(i, j)-(1-epsilon))
+	}
+	delta.Scale(1/float64(r), delta)
+	return delta
+}
+
+// lossName names a built-in loss for serialization, recording Huber's
+// Delta as parameterizedName does for activations. It reports false for a
+// custom Loss.
+func lossName(loss Loss) (string, bool) {
+	switch l := loss.(type) {
+	case MSE:
+		return "mse", true
+	case MAE:
+		return "mae", true
+	case Huber:
+		return parameterizedName("huber", l.Delta), true
+	case CrossEntropy:
+		return "cross_entropy", true
+	case CategoricalCrossEntropy:
+		return "categorical_cross_entropy", true
+	case SparseCategoricalCrossEntropy:
+		return "sparse_categorical_cross_entropy", true
+	}
+	return "", false
+}
+
+// lossByName returns the built-in loss named by lossName
+func lossByName(name string) (Loss, bool) {
+	switch name {
+	case "mse":
+		return MSE{}, true
+	case "mae":
+		return MAE{}, true
+	case "cross_entropy":
+		return CrossEntropy{}, true
+	case "categorical_cross_entropy":
+		return CategoricalCrossEntropy{}, true
+	case "sparse_categorical_cross_entropy":
+		return SparseCategoricalCrossEntropy{}, true
+	}
+	param, ok := strings.CutPrefix(name, "huber(")
+	if !ok || !strings.HasSuffix(param, ")") {
+		return nil, false
+	}
+	delta, err := strconv.ParseFloat(strings.TrimSuffix(param, ")"), 64)
+	if err != nil {
+		return nil, false
+	}
+	return Huber{Delta: delta}, true
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestCrossEntropySteeperThanMSE(t *testing.T) {
+	// A sigmoid output of 0.99 for a target of 0 is confidently wrong.
+	// Through the sigmoid, MSE's gradient vanishes with p(1-p) while
+	// cross-entropy's stays p - t.
+	pred := mat.NewDense(1, 1, []float64{0.99})
+	target := mat.NewDense(1, 1, []float64{0})
+	throughSigmoid := func(loss Loss) float64 {
+		p := pred.At(0, 0)
+		return loss.Gradient(pred, target).At(0, 0) * sigmoidDerivative(p)
+	}
+	mse, ce := throughSigmoid(MSE{}), throughSigmoid(CrossEntropy{})
+	if math.Abs(ce-0.99) > 1e-9 {
+		t.Errorf("cross-entropy gradient %v, want p - t = 0.99", ce)
+	}
+	if ce <= 10*mse {
+		t.Errorf("cross-entropy gradient %v is not much steeper than MSE's %v", ce, mse)
+	}
+}
+
+func TestHuber(t *testing.T) {
+	h := Huber{Delta: 1}
+	// Huber is half the squared error for small residuals
+	pred := mat.NewDense(2, 1, []float64{0.3, -0.2})
+	target := mat.NewDense(2, 1, []float64{0, 0})
+	if got, want := h.Loss(pred, target), (MSE{}).Loss(pred, target)/2; math.Abs(got-want) > 1e-15 {
+		t.Errorf("small-residual loss %v, want half of MSE %v", got, want)
+	}
+	half := new(mat.Dense)
+	half.Scale(0.5, (MSE{}).Gradient(pred, target))
+	if got := h.Gradient(pred, target); !mat.EqualApprox(got, half, 1e-15) {
+		t.Errorf("small-residual gradient %v, want half of MSE's %v", got.RawMatrix().Data, half.RawMatrix().Data)
+	}
+
+	pred = mat.NewDense(2, 1, []float64{10, -0.5})
+	if got, want := h.Loss(pred, target), (1*(10-0.5)+0.125)/2; math.Abs(got-want) > 1e-15 {
+		t.Errorf("large-residual loss %v, want %v", got, want)
+	}
+	want := mat.NewDense(2, 1, []float64{0.5, -0.25})
+	if got := h.Gradient(pred, target); !mat.EqualApprox(got, want, 1e-15) {
+		t.Errorf("large-residual gradient %v, want %v", got.RawMatrix().Data, want.RawMatrix().Data)
+	}
+}
+
+func TestMAEGradient(t *testing.T) {
+	pred := mat.NewDense(2, 2, []float64{1, -1, 0.5, 2})
+	target := mat.NewDense(2, 2, []float64{0, 0, 0.5, 3})
+	want := mat.NewDense(2, 2, []float64{0.25, -0.25, 0, -0.25})
+	if got := (MAE{}).Gradient(pred, target); !mat.Equal(got, want) {
+		t.Errorf("gradient %v, want %v", got.RawMatrix().Data, want.RawMatrix().Data)
+	}
+	if got := (MAE{}).Loss(pred, target); got != 0.75 {
+		t.Errorf("loss %v, want 0.75", got)
+	}
+}
+
+func TestSparseMatchesOneHot(t *testing.T) {
+	pred := Softmax(randomDense(5, 4, 1))
+	labels := []int{3, 0, 1, 1, 2}
+	sparse := mat.NewDense(5, 1, nil)
+	for i, label := range labels {
+		sparse.Set(i, 0, float64(label))
+	}
+	oneHot := OneHot(labels, 4)
+
+	if got, want := (SparseCategoricalCrossEntropy{}).Loss(pred, sparse), (CategoricalCrossEntropy{}).Loss(pred, oneHot); math.Abs(got-want) > 1e-15 {
+		t.Errorf("sparse loss %v, one-hot loss %v", got, want)
+	}
+	if got, want := (SparseCategoricalCrossEntropy{}).Gradient(pred, sparse), (CategoricalCrossEntropy{}).Gradient(pred, oneHot); !mat.Equal(got, want) {
+		t.Errorf("sparse gradient %v, one-hot gradient %v", got.RawMatrix().Data, want.RawMatrix().Data)
+	}
+	for _, epsilon := range []float64{0, 0.1} {
+		got := softmaxSparseCrossEntropyDelta(pred, sparse, epsilon)
+		want := softmaxCrossEntropyDelta(pred, SmoothLabels(oneHot, epsilon))
+		if !mat.EqualApprox(got, want, 1e-15) {
+			t.Errorf("smoothing %v: sparse softmax delta %v, one-hot %v", epsilon, got.RawMatrix().Data, want.RawMatrix().Data)
+		}
+	}
+	sparse.Set(0, 0, 4)
+	assertPanics(t, "sparse loss with label 4 of 4 classes", func() { (SparseCategoricalCrossEntropy{}).Loss(pred, sparse) })
+}
+
+func TestSparseTraining(t *testing.T) {
+	inputs, oneHot := threeClassData()
+	labels := mat.NewDense(90, 1, nil)
+	for i, label := range ArgmaxRows(oneHot) {
+		labels.Set(i, 0, float64(label))
+	}
+	train := func(loss Loss, targets *mat.Dense) *NeuralNetwork {
+		nn := New([]int{2, 4, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(loss), WithLabelSmoothing(0.1))
+		if _, err := nn.Train(inputs, targets, 50, 0.5); err != nil {
+			t.Fatal(err)
+		}
+		return nn
+	}
+	sparse, dense := train(SparseCategoricalCrossEntropy{}, labels), train(CategoricalCrossEntropy{}, oneHot)
+	for l, w := range sparse.Weights() {
+		if !mat.EqualApprox(w, dense.Weights()[l], 1e-12) {
+			t.Errorf("layer %d: smoothed sparse training differs from smoothed one-hot training", l)
+		}
+	}
+
+	normalized := New([]int{2, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(SparseCategoricalCrossEntropy{}), WithTargetNormalization())
+	if _, err := normalized.Train(inputs, labels, 1, 0.5); err == nil {
+		t.Error("target normalization of class labels gave no error")
+	}
+	sigmoid := New([]int{2, 3}, WithSeed(1), WithLoss(SparseCategoricalCrossEntropy{}), WithLabelSmoothing(0.1))
+	if _, err := sigmoid.Train(inputs, labels, 1, 0.5); err == nil {
+		t.Error("sparse label smoothing without a softmax output gave no error")
+	}
+}
+
+func TestCrossEntropyClipsProbabilities(t *testing.T) {
+	pred := mat.NewDense(2, 2, []float64{0, 1, 1, 0})
+	target := mat.NewDense(2, 2, []float64{1, 0, 1, 0})
+	for _, loss := range []Loss{CrossEntropy{}, CategoricalCrossEntropy{}} {
+		l := loss.Loss(pred, target)
+		if math.IsInf(l, 0) || math.IsNaN(l) || l < 1 {
+			t.Errorf("%T loss of a confident wrong prediction = %v, want large and finite", loss, l)
+		}
+		grad := loss.Gradient(pred, target)
+		r, c := grad.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if g := grad.At(i, j); math.IsInf(g, 0) || math.IsNaN(g) {
+					t.Errorf("%T gradient[%d][%d] = %v, want finite", loss, i, j, g)
+				}
+			}
+		}
+	}
+	if got := clipProbability(0); got != probabilityEpsilon {
+		t.Errorf("clipProbability(0) = %v, want %v", got, probabilityEpsilon)
+	}
+	if got := clipProbability(1); got != 1-probabilityEpsilon {
+		t.Errorf("clipProbability(1) = %v, want %v", got, 1-probabilityEpsilon)
+	}
+}
+
+func TestSparseLabelsChecked(t *testing.T) {
+	inputs := mat.NewDense(2, 2, []float64{0, 1, 1, 0})
+	nn := New([]int{2, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(SparseCategoricalCrossEntropy{}))
+	for _, bad := range []float64{1.5, -1, 3, math.NaN()} {
+		labels := mat.NewDense(2, 1, []float64{0, bad})
+		if _, err := nn.Train(inputs, labels, 1, 0.5); err == nil || !strings.Contains(err.Error(), "row 1") {
+			t.Errorf("label %v gave error %v, want one naming row 1", bad, err)
+		}
+	}
+	if err := nn.CheckDims(inputs, mat.NewDense(2, 1, []float64{0, 2})); err != nil {
+		t.Errorf("valid labels: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// findLRBatchSize is the number of samples in each step of FindLR
+const findLRBatchSize = 32
+
+// FindLR runs the learning-rate range test: it takes steps training steps
+// on consecutive mini-batches of up to 32 samples, cycling through inputs
+// and targets, while raising the learning rate exponentially from minLR to
+// maxLR, and returns each step's rate and the loss measured before its
+// update. Plotting losses against lrs shows where the loss falls fastest,
+// a good learning rate, and where it diverges. The test trains a Clone,
+// so the network's parameters, optimizer and random stream are left as
+// they were. A custom Optimizer, which Clone cannot recreate, is shared
+// with the clone and ends up holding state for the clone's matrices as
+// well as the network's; the built-in optimizers start afresh. It panics
+// unless 0 < minLR <= maxLR and steps is positive, or if the data does not
+// match the network.
+func (nn *NeuralNetwork) FindLR(inputs, targets *mat.Dense, minLR, maxLR float64, steps int) (lrs, losses []float64) {
+	if minLR <= 0 || maxLR < minLR {
+		panic(fmt.Sprintf("nngo: learning rate range [%v, %v] must be positive and increasing", minLR, maxLR))
+	}
+	if steps <= 0 {
+		panic(fmt.Sprintf("nngo: %d learning rate finder steps, must be positive", steps))
+	}
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		panic(err)
+	}
+
+	trial := nn.Clone()
+	targets = trial.trainingTargets(targets)
+	rows, inCols := inputs.Dims()
+	_, outCols := targets.Dims()
+	lrs = make([]float64, steps)
+	losses = make([]float64, steps)
+	start := 0
+	for step := range lrs {
+		lrs[step] = minLR
+		if steps > 1 {
+			lrs[step] = minLR * math.Pow(maxLR/minLR, float64(step)/float64(steps-1))
+		}
+		if start >= rows {
+			start = 0
+		}
+		end := min(start+findLRBatchSize, rows)
+		batchInputs := inputs.Slice(start, end, 0, inCols).(*mat.Dense)
+		batchTargets := targets.Slice(start, end, 0, outCols).(*mat.Dense)
+		losses[step] = trial.trainStep(batchInputs, batchTargets, trial.uniformRates(lrs[step]))
+		start = end
+	}
+	return lrs, losses
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFindLR(t *testing.T) {
+	inputs := randomDense(100, 3, 1)
+	targets := randomDense(100, 1, 2)
+	nn := New([]int{3, 8, 1}, WithSeed(1), WithOutputActivation(Linear))
+	before := nn.Weights()
+
+	lrs, losses := nn.FindLR(inputs, targets, 1e-4, 100, 60)
+	if len(lrs) != 60 || len(losses) != 60 {
+		t.Fatalf("got %d rates and %d losses, want 60 of each", len(lrs), len(losses))
+	}
+	if math.Abs(lrs[0]-1e-4) > 1e-12 || math.Abs(lrs[59]-100) > 1e-9 {
+		t.Errorf("rates run from %v to %v, want 1e-4 to 100", lrs[0], lrs[59])
+	}
+	for i := 1; i < len(lrs); i++ {
+		if lrs[i] <= lrs[i-1] {
+			t.Fatalf("rate %d = %v does not increase from %v", i, lrs[i], lrs[i-1])
+		}
+	}
+	best := math.Inf(1)
+	for _, l := range losses {
+		best = math.Min(best, l)
+	}
+	if last := losses[len(losses)-1]; !(math.IsNaN(last) || last > 10*best) {
+		t.Errorf("final loss %v at rate %v, want divergence well above the best %v", last, lrs[59], best)
+	}
+
+	for l, w := range nn.Weights() {
+		if !mat.Equal(w, before[l]) {
+			t.Errorf("FindLR changed the network's layer %d weights", l)
+		}
+	}
+
+	assertPanics(t, "FindLR with minLR 0", func() { nn.FindLR(inputs, targets, 0, 1, 10) })
+	assertPanics(t, "FindLR with no steps", func() { nn.FindLR(inputs, targets, 1e-3, 1, 0) })
+}
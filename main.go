@@ -1,119 +1,1345 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
-	"time"
 
 	"gonum.org/v1/gonum/mat"
 )
 
-// Activation function and its derivative (Sigmoid)
-func sigmoid(x float64) float64 {
-	return 1.0 / (1.0 + math.Exp(-x))
+// NeuralNetwork structure
+type NeuralNetwork struct {
+	// layerSizes lists the number of units in each layer, input first
+	layerSizes []int
+	// weights[l] maps layer l to layer l+1 and is layerSizes[l+1] x
+	// layerSizes[l], with pieces times as many rows for a Maxout layer
+	weights []*mat.Dense
+	// biases[l] is the bias row of layer l+1, one entry per weight row
+	biases []*mat.Dense
+	// activations[l] is applied to the output of weights[l]
+	activations []Activation
+	// batchNorms[l] normalizes the output of weights[l] before its
+	// activation, or is nil when that layer has no batch normalization
+	batchNorms []*batchNorm
+	// init is the strategy used to draw initial weights
+	init InitStrategy
+	// frozen[l], when set, keeps weights[l] and biases[l] fixed in training
+	frozen []bool
+	// ties make the weights of each decoder layer the transpose of its
+	// encoder's, see WithTiedWeights
+	ties []weightTie
+	// optimizer applies the weight and bias updates computed by Train
+	optimizer Optimizer
+	// loss is the objective Train minimizes
+	loss Loss
+	// shuffle permutes the sample order every epoch of TrainMiniBatch
+	shuffle bool
+	// l1 and l2 are the coefficients of the L1 and L2 penalties whose
+	// gradients are added to every weight gradient
+	l1, l2 float64
+	// maxGradNorm caps the global L2 norm of each step's gradients
+	maxGradNorm float64
+	// accumulationSteps is the number of TrainMiniBatch batches whose
+	// gradients are averaged into each update
+	accumulationSteps int
+	// normalizeTargets enables target normalization, whose per-column
+	// mean and standard deviation are fitted by the first training call
+	normalizeTargets      bool
+	targetMean, targetStd []float64
+	// validateInputs makes training reject NaN and infinite data, see
+	// ValidateInputs
+	validateInputs bool
+	// labelSmoothing is the epsilon of SmoothLabels applied to training
+	// targets
+	labelSmoothing float64
+	// task selects the metric Evaluate reports
+	task Task
+	// learningRate is the rate Fit trains with, 0 meaning
+	// defaultLearningRate
+	learningRate float64
+	// dropout is the probability of dropping each hidden unit in training
+	dropout float64
+	// outputTransform, when set, maps every Predict result
+	outputTransform func(*mat.Dense) *mat.Dense
+	// progress, when set, is called after every training epoch
+	progress func(epoch int, loss float64)
+	// checkpointEvery, when positive, saves the network to checkpointDir
+	// after every checkpointEvery epochs
+	checkpointEvery int
+	checkpointDir   string
+	// partialEpochs counts the epochs trained by PartialFit, and
+	// epochOffset, set to it while PartialFit trains, is added to the epoch
+	// numbers given to progress and to checkpoints
+	partialEpochs, epochOffset int
+	// deterministic keeps every computation on the calling goroutine
+	deterministic bool
+	// scratch is reused by every training step
+	scratch *trainScratch
+	// vector is reused by every PredictVector call
+	vector *vectorBuffers
+	// rng drives weight initialization and training-time randomness such
+	// as shuffling; rngSource is its source, kept so Clone can copy it
+	rng       *rand.Rand
+	rngSource *pcgSource
 }
 
-func sigmoidDerivative(x float64) float64 {
-	return x * (1.0 - x)
+// NewNeuralNetwork creates a new neural network with the given layer sizes,
+// input layer first, using sigmoid activations on every layer
+func NewNeuralNetwork(layerSizes []int) *NeuralNetwork {
+	return New(layerSizes)
 }
 
-// NeuralNetwork structure
-type NeuralNetwork struct {
-	inputLayerSize      int
-	hiddenLayerSize     int
-	outputLayerSize     int
-	weightsInputHidden  *mat.Dense
-	weightsHiddenOutput *mat.Dense
+// NewNeuralNetworkWithActivations creates a new neural network with the
+// given layer sizes, using hiddenActivation on every hidden layer and
+// outputActivation on the output layer
+func NewNeuralNetworkWithActivations(layerSizes []int, hiddenActivation, outputActivation Activation) *NeuralNetwork {
+	return New(layerSizes, WithActivation(hiddenActivation), WithOutputActivation(outputActivation))
 }
 
-// NewNeuralNetwork creates a new neural network with the given sizes
-func NewNeuralNetwork(inputLayerSize, hiddenLayerSize, outputLayerSize int) *NeuralNetwork {
-	rand.Seed(time.Now().UnixNano())
+// NewNeuralNetworkWithSeed creates a new sigmoid network like
+// NewNeuralNetwork whose weight initialization and training randomness
+// come from seed, so equal seeds give identical networks
+func NewNeuralNetworkWithSeed(layerSizes []int, seed int64) *NeuralNetwork {
+	return New(layerSizes, WithSeed(seed))
+}
 
-	weightsInputHidden := mat.NewDense(hiddenLayerSize, inputLayerSize, nil)
-	weightsHiddenOutput := mat.NewDense(outputLayerSize, hiddenLayerSize, nil)
+// NewNeuralNetworkWithInit creates a new sigmoid network seeded like
+// NewNeuralNetworkWithSeed whose initial weights are drawn using init
+func NewNeuralNetworkWithInit(layerSizes []int, init InitStrategy, seed int64) *NeuralNetwork {
+	return New(layerSizes, WithInit(init), WithSeed(seed))
+}
 
-	for i := 0; i < hiddenLayerSize; i++ {
-		for j := 0; j < inputLayerSize; j++ {
-			weightsInputHidden.Set(i, j, rand.Float64())
+func newNeuralNetwork(layerSizes []int, config networkConfig) *NeuralNetwork {
+	if len(layerSizes) < 2 {
+		panic(fmt.Sprintf("nngo: need at least an input and an output layer, got %d layers", len(layerSizes)))
+	}
+	for i, size := range layerSizes {
+		if size <= 0 {
+			panic(fmt.Sprintf("nngo: layer %d has size %d, must be positive", i, size))
 		}
 	}
+	if config.hiddenActivation.rowFunc != nil && len(layerSizes) > 2 {
+		panic(fmt.Sprintf("nngo: %s can only be used as the output activation", config.hiddenActivation.Name))
+	}
+
+	rng, rngSource := newRand(config.seed)
+
+	numLayers := len(layerSizes) - 1
+	weights := make([]*mat.Dense, numLayers)
+	biases := make([]*mat.Dense, numLayers)
+	activations := make([]Activation, numLayers)
+	batchNorms := make([]*batchNorm, numLayers)
 
-	for i := 0; i < outputLayerSize; i++ {
-		for j := 0; j < hiddenLayerSize; j++ {
-			weightsHiddenOutput.Set(i, j, rand.Float64())
+	for l := 0; l < numLayers; l++ {
+		activations[l] = config.hiddenActivation
+		if l == numLayers-1 {
+			activations[l] = config.outputActivation
+		}
+		fanIn, width := layerSizes[l], activations[l].inputWidth(layerSizes[l+1])
+		weights[l] = mat.NewDense(width, fanIn, nil)
+		config.init.fill(weights[l], rng)
+		biases[l] = mat.NewDense(1, width, nil)
+		if config.batchNorm && l < numLayers-1 {
+			batchNorms[l] = newBatchNorm(width)
 		}
 	}
 
 	return &NeuralNetwork{
-		inputLayerSize:      inputLayerSize,
-		hiddenLayerSize:     hiddenLayerSize,
-		outputLayerSize:     outputLayerSize,
-		weightsInputHidden:  weightsInputHidden,
-		weightsHiddenOutput: weightsHiddenOutput,
+		layerSizes:  append([]int(nil), layerSizes...),
+		weights:     weights,
+		biases:      biases,
+		activations: activations,
+		batchNorms:  batchNorms,
+		init:        config.init,
+		optimizer:   &SGD{},
+		loss:        MSE{},
+		rng:         rng,
+		rngSource:   rngSource,
+	}
+}
+
+// SetOptimizer replaces the optimizer Train uses to update weights and
+// biases. Networks start out with plain SGD.
+func (nn *NeuralNetwork) SetOptimizer(optimizer Optimizer) {
+	nn.optimizer = optimizer
+}
+
+// Train the neural network.
+// inputs holds one sample per row (samples x input layer size) and targets
+// holds the matching expected outputs (samples x output layer size).
+// The returned slice holds the loss of each epoch's feedforward pass,
+// measured before that epoch's weight update. Mismatched dimensions are
+// reported as an error before any training happens, see CheckDims.
+func (nn *NeuralNetwork) Train(inputs, targets *mat.Dense, epochs int, learningRate float64) ([]float64, error) {
+	return nn.TrainSchedule(inputs, targets, epochs, ConstantLR(learningRate))
+}
+
+// TrainSchedule trains like Train but takes each epoch's learning rate from
+// schedule
+func (nn *NeuralNetwork) TrainSchedule(inputs, targets *mat.Dense, epochs int, schedule LearningRateSchedule) ([]float64, error) {
+	return nn.trainSchedule(context.Background(), inputs, targets, epochs, schedule)
+}
+
+// TrainContext trains like Train but checks ctx before every epoch. Once
+// ctx is cancelled or its deadline passes it stops and returns the loss
+// history of the epochs completed so far along with ctx.Err().
+func (nn *NeuralNetwork) TrainContext(ctx context.Context, inputs, targets *mat.Dense, epochs int, learningRate float64) ([]float64, error) {
+	return nn.trainSchedule(ctx, inputs, targets, epochs, ConstantLR(learningRate))
+}
+
+func (nn *NeuralNetwork) trainSchedule(ctx context.Context, inputs, targets *mat.Dense, epochs int, schedule LearningRateSchedule) ([]float64, error) {
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		return nil, err
+	}
+	targets = nn.trainingTargets(targets)
+	history := make([]float64, 0, epochs)
+	for epoch := 0; epoch < epochs; epoch++ {
+		if err := ctx.Err(); err != nil {
+			return history, err
+		}
+		history = append(history, nn.trainStep(inputs, targets, nn.uniformRates(schedule(epoch))))
+		if err := nn.finishEpoch(epoch, history[epoch]); err != nil {
+			return history, err
+		}
+	}
+	return history, nil
+}
+
+// TrainWeighted trains like Train but scales each sample's contribution to
+// the gradient by its entry of sampleWeights, one per row of inputs, for
+// example to upweight rare classes in an imbalanced dataset. A weight of 2
+// counts a sample as if it appeared twice and 0 ignores it. A nil
+// sampleWeights weights every sample equally and matches Train. The
+// returned losses are unweighted.
+func (nn *NeuralNetwork) TrainWeighted(inputs, targets *mat.Dense, sampleWeights []float64, epochs int, learningRate float64) ([]float64, error) {
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		return nil, err
+	}
+	if sampleWeights != nil {
+		if rows, _ := inputs.Dims(); len(sampleWeights) != rows {
+			return nil, fmt.Errorf("nngo: got %d sample weights for %d samples", len(sampleWeights), rows)
+		}
+		for i, w := range sampleWeights {
+			if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return nil, fmt.Errorf("nngo: sample %d has weight %v, must be finite and non-negative", i, w)
+			}
+		}
+	}
+	targets = nn.trainingTargets(targets)
+	rates := nn.uniformRates(learningRate)
+	history := make([]float64, 0, epochs)
+	for epoch := 0; epoch < epochs; epoch++ {
+		history = append(history, nn.trainWeightedStep(inputs, targets, sampleWeights, rates))
+		if err := nn.finishEpoch(epoch, history[epoch]); err != nil {
+			return history, err
+		}
 	}
+	return history, nil
 }
 
-// Train the neural network
-func (nn *NeuralNetwork) Train(inputs, targets *mat.Dense, epochs int, learningRate float64) {
+// TrainSafe trains like Train but checks every weight and bias, and any
+// batch normalization scales, shifts and running statistics, after each
+// epoch. As soon as one is NaN or infinite, typically because the learning
+// rate is too high, it restores the parameters from before that epoch and
+// returns the history so far with an error naming the epoch and layer.
+// The optimizer state accumulated during the run may be corrupted too, so
+// a built-in optimizer is replaced by a fresh one with the same settings,
+// as in Reset; a custom Optimizer is kept as it is.
+func (nn *NeuralNetwork) TrainSafe(inputs, targets *mat.Dense, epochs int, learningRate float64) ([]float64, error) {
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		return nil, err
+	}
+	targets = nn.trainingTargets(targets)
+	rates := nn.uniformRates(learningRate)
+	history := make([]float64, 0, epochs)
 	for epoch := 0; epoch < epochs; epoch++ {
-		// Feedforward
-		hiddenInput := mat.NewDense(0, 0, nil)
-		hiddenInput.Mul(nn.weightsInputHidden, inputs)
-		hiddenOutput := applyActivation(hiddenInput, sigmoid)
+		weights, biases := nn.copyParameters()
+		batchNorms := nn.copyBatchNorms()
+		loss := nn.trainStep(inputs, targets, rates)
+		if err := nn.checkFinite(); err != nil {
+			for l := range nn.weights {
+				nn.weights[l].Copy(weights[l])
+				nn.biases[l].Copy(biases[l])
+				if bn := nn.batchNorms[l]; bn != nil {
+					bn.copyFrom(batchNorms[l])
+				}
+			}
+			nn.optimizer = freshOptimizer(nn.optimizer)
+			return history, fmt.Errorf("nngo: epoch %d: %w", epoch, err)
+		}
+		history = append(history, loss)
+		if err := nn.finishEpoch(epoch, loss); err != nil {
+			return history, err
+		}
+	}
+	return history, nil
+}
+
+// checkFinite reports the first layer with a NaN or infinite parameter or
+// batch normalization running statistic
+func (nn *NeuralNetwork) checkFinite() error {
+	for l := range nn.weights {
+		if !allFinite(nn.weights[l]) {
+			return fmt.Errorf("layer %d weights are not finite", l+1)
+		}
+		if !allFinite(nn.biases[l]) {
+			return fmt.Errorf("layer %d biases are not finite", l+1)
+		}
+		if bn := nn.batchNorms[l]; bn != nil {
+			if !allFinite(bn.gamma) || !allFinite(bn.beta) {
+				return fmt.Errorf("layer %d batch normalization parameters are not finite", l+1)
+			}
+			if !allFinite(bn.runningMean) || !allFinite(bn.runningVar) {
+				return fmt.Errorf("layer %d batch normalization running statistics are not finite", l+1)
+			}
+		}
+	}
+	return nil
+}
+
+// allFinite reports whether m holds no NaN or infinite values
+func allFinite(m *mat.Dense) bool {
+	r, c := m.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if v := m.At(i, j); math.IsNaN(v) || math.IsInf(v, 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TrainLayerRates trains like Train but updates each layer with its own
+// learning rate, learningRates[l] for weights[l] with the input side first,
+// for example to fine-tune pretrained early layers more gently. A single
+// rate applies to every layer.
+func (nn *NeuralNetwork) TrainLayerRates(inputs, targets *mat.Dense, epochs int, learningRates []float64) ([]float64, error) {
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		return nil, err
+	}
+	targets = nn.trainingTargets(targets)
+	rates := learningRates
+	switch len(learningRates) {
+	case len(nn.weights):
+	case 1:
+		rates = nn.uniformRates(learningRates[0])
+	default:
+		return nil, fmt.Errorf("nngo: got %d learning rates, network has %d layers", len(learningRates), len(nn.weights))
+	}
+	history := make([]float64, 0, epochs)
+	for epoch := 0; epoch < epochs; epoch++ {
+		history = append(history, nn.trainStep(inputs, targets, rates))
+		if err := nn.finishEpoch(epoch, history[epoch]); err != nil {
+			return history, err
+		}
+	}
+	return history, nil
+}
+
+// TrainSample takes one training step on a single example, for online
+// learning as data arrives, and returns the loss on it before the update.
+// Batch normalization cannot learn from single-sample batches, whose
+// statistics normalize every unit to zero.
+func (nn *NeuralNetwork) TrainSample(input, target []float64, learningRate float64) (float64, error) {
+	if want := nn.layerSizes[0]; len(input) != want {
+		return 0, fmt.Errorf("nngo: input has %d features, network expects %d", len(input), want)
+	}
+	if len(target) == 0 {
+		return 0, errors.New("nngo: target has no outputs")
+	}
+	inputs := mat.NewDense(1, len(input), input)
+	targets := mat.NewDense(1, len(target), target)
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		return 0, err
+	}
+	targets = nn.trainingTargets(targets)
+	return nn.trainStep(inputs, targets, nn.uniformRates(learningRate)), nil
+}
+
+// uniformRates returns learningRate for every layer
+func (nn *NeuralNetwork) uniformRates(learningRate float64) []float64 {
+	rates := make([]float64, len(nn.weights))
+	for l := range rates {
+		rates[l] = learningRate
+	}
+	return rates
+}
+
+// TrainMiniBatch trains like Train but splits the samples into consecutive
+// batches of batchSize rows, updating the weights after each batch. The
+// last batch of an epoch is smaller when batchSize does not divide the
+// number of samples. Each history entry is the loss averaged over all
+// samples of that epoch. See SetShuffle to vary the batches between epochs
+// and WithAccumulationSteps to update only every few batches.
+func (nn *NeuralNetwork) TrainMiniBatch(inputs, targets *mat.Dense, epochs, batchSize int, learningRate float64) ([]float64, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("nngo: batch size %d, must be positive", batchSize)
+	}
+	if err := nn.checkTrainingData(inputs, targets); err != nil {
+		return nil, err
+	}
+	targets = nn.trainingTargets(targets)
+	rows, inCols := inputs.Dims()
+	_, outCols := targets.Dims()
+
+	rates := nn.uniformRates(learningRate)
+	history := make([]float64, 0, epochs)
+	for epoch := 0; epoch < epochs; epoch++ {
+		epochInputs, epochTargets := inputs, targets
+		if nn.shuffle {
+			epochInputs, epochTargets = shuffleRows(inputs, targets, nn.rng)
+		}
+
+		total := 0.0
+		pending := 0
+		for start := 0; start < rows; start += batchSize {
+			end := min(start+batchSize, rows)
+			batchInputs := epochInputs.Slice(start, end, 0, inCols).(*mat.Dense)
+			batchTargets := epochTargets.Slice(start, end, 0, outCols).(*mat.Dense)
+			if nn.accumulationSteps <= 1 {
+				total += nn.trainStep(batchInputs, batchTargets, rates) * float64(end-start)
+				continue
+			}
+			total += nn.accumulateStep(batchInputs, batchTargets, pending == 0) * float64(end-start)
+			if pending++; pending == nn.accumulationSteps || end == rows {
+				nn.applyAccumulated(pending, rates)
+				pending = 0
+			}
+		}
+		history = append(history, total/float64(rows))
+		if err := nn.finishEpoch(epoch, history[epoch]); err != nil {
+			return history, err
+		}
+	}
+	return history, nil
+}
+
+// TrainWithValidation trains on trainIn/trainTgt like Train for at most
+// maxEpochs, measuring the loss on valIn/valTgt after every epoch. Training
+// stops once the validation loss has not improved for patience consecutive
+// epochs, and the weights from the best validation epoch are restored.
+// The returned slice holds the validation loss of every epoch that ran.
+func (nn *NeuralNetwork) TrainWithValidation(trainIn, trainTgt, valIn, valTgt *mat.Dense, maxEpochs int, learningRate float64, patience int) ([]float64, error) {
+	if err := nn.checkTrainingData(trainIn, trainTgt); err != nil {
+		return nil, err
+	}
+	trainTgt = nn.trainingTargets(trainTgt)
+	if err := nn.checkTrainingData(valIn, valTgt); err != nil {
+		return nil, fmt.Errorf("validation set: %w", err)
+	}
+	history := make([]float64, 0, maxEpochs)
+	bestLoss := math.Inf(1)
+	bestWeights, bestBiases := nn.copyParameters()
+	bestBatchNorms := nn.copyBatchNorms()
+	sinceBest := 0
+	rates := nn.uniformRates(learningRate)
+
+	for epoch := 0; epoch < maxEpochs; epoch++ {
+		nn.trainStep(trainIn, trainTgt, rates)
+		valLoss := nn.loss.Loss(nn.predict(valIn), valTgt)
+		history = append(history, valLoss)
+		if err := nn.finishEpoch(epoch, valLoss); err != nil {
+			return history, err
+		}
+
+		if valLoss < bestLoss {
+			bestLoss = valLoss
+			bestWeights, bestBiases = nn.copyParameters()
+			bestBatchNorms = nn.copyBatchNorms()
+			sinceBest = 0
+		} else if sinceBest++; sinceBest >= patience {
+			break
+		}
+	}
+
+	// Copy rather than swap the matrices so optimizer state keyed by
+	// them stays attached
+	for l := range nn.weights {
+		nn.weights[l].Copy(bestWeights[l])
+		nn.biases[l].Copy(bestBiases[l])
+		if bn := nn.batchNorms[l]; bn != nil {
+			bn.copyFrom(bestBatchNorms[l])
+		}
+	}
+	return history, nil
+}
+
+// finishEpoch passes an epoch's loss to the progress callback, if any, and
+// writes a checkpoint when one is due
+func (nn *NeuralNetwork) finishEpoch(epoch int, loss float64) error {
+	epoch += nn.epochOffset
+	if nn.progress != nil {
+		nn.progress(epoch, loss)
+	}
+	if nn.checkpointEvery > 0 && (epoch+1)%nn.checkpointEvery == 0 {
+		return nn.writeCheckpoint(epoch + 1)
+	}
+	return nil
+}
+
+// copyParameters returns deep copies of the weight and bias matrices
+func (nn *NeuralNetwork) copyParameters() (weights, biases []*mat.Dense) {
+	weights = make([]*mat.Dense, len(nn.weights))
+	biases = make([]*mat.Dense, len(nn.biases))
+	for l := range nn.weights {
+		weights[l] = mat.DenseCopyOf(nn.weights[l])
+		biases[l] = mat.DenseCopyOf(nn.biases[l])
+	}
+	return weights, biases
+}
+
+// Clone returns a deep copy of the network. Weights, biases and batch
+// normalization parameters are copied, so training either network leaves
+// the other unchanged. The built-in optimizers are replaced by ones with the
+// same settings and no accumulated state; any other Optimizer is shared,
+// which is safe because optimizers keep state per parameter matrix. The
+// clone's random stream continues from a copy of the original's state, so
+// both draw the same dropout masks and shuffles until they diverge, and
+// cloning never advances the original's stream. The clone writes no
+// checkpoints, so training it cannot overwrite the original's. A progress
+// callback is shared and also sees the clone's epochs.
+func (nn *NeuralNetwork) Clone() *NeuralNetwork {
+	clone := *nn
+	clone.layerSizes = append([]int(nil), nn.layerSizes...)
+	clone.weights, clone.biases = nn.copyParameters()
+	clone.activations = append([]Activation(nil), nn.activations...)
+	clone.frozen = append([]bool(nil), nn.frozen...)
+	clone.ties = append([]weightTie(nil), nn.ties...)
+	clone.targetMean = append([]float64(nil), nn.targetMean...)
+	clone.targetStd = append([]float64(nil), nn.targetStd...)
+	clone.batchNorms = nn.copyBatchNorms()
+	clone.optimizer = freshOptimizer(nn.optimizer)
+	clone.scratch = nil
+	clone.vector = nil
+	clone.rng, clone.rngSource = nn.rngSource.clone()
+	clone.checkpointEvery, clone.checkpointDir = 0, ""
+	return &clone
+}
+
+// Weights returns a copy of every layer's weight matrix, input layer
+// first. weights[l] is layerSizes[l+1] x layerSizes[l], one row per unit
+// of layer l+1, or one row per piece of each unit of a Maxout layer.
+func (nn *NeuralNetwork) Weights() []*mat.Dense {
+	weights, _ := nn.copyParameters()
+	return weights
+}
+
+// SetWeights replaces every layer's weights with copies of weights, shaped
+// like those returned by Weights. Nothing is changed if any matrix has the
+// wrong shape. Optimizer state is kept. Tied decoder layers take the
+// transpose of their encoder's new weights rather than their own entry.
+func (nn *NeuralNetwork) SetWeights(weights []*mat.Dense) error {
+	if len(weights) != len(nn.weights) {
+		return fmt.Errorf("nngo: got %d weight matrices, network has %d layers", len(weights), len(nn.weights))
+	}
+	for l := range nn.weights {
+		if err := sameShape(weights[l], nn.weights[l]); err != nil {
+			return fmt.Errorf("nngo: layer %d weights: %w", l+1, err)
+		}
+	}
+	for l := range nn.weights {
+		nn.weights[l].Copy(weights[l])
+	}
+	nn.syncTiedWeights()
+	return nil
+}
+
+// Reset reinitializes the network for a new training run without
+// rebuilding it: the weights are redrawn with the network's init strategy
+// from a generator seeded by seed, exactly as New would with WithSeed(seed),
+// biases are zeroed, batch normalization starts over, the optimizer's
+// state is dropped, any fitted target normalization is forgotten and
+// PartialFit numbers epochs from 0 again. Layer sizes, activations and
+// training settings are kept.
+func (nn *NeuralNetwork) Reset(seed int64) {
+	nn.rng, nn.rngSource = newRand(seed)
+	for l, w := range nn.weights {
+		nn.init.fill(w, nn.rng)
+		nn.biases[l].Zero()
+		if bn := nn.batchNorms[l]; bn != nil {
+			_, units := bn.gamma.Dims()
+			bn.copyFrom(newBatchNorm(units))
+		}
+	}
+	nn.syncTiedWeights()
+	nn.optimizer = freshOptimizer(nn.optimizer)
+	nn.targetMean, nn.targetStd = nil, nil
+	nn.partialEpochs = 0
+}
+
+// setActivation makes activation layer l's, redrawing the layer's weights
+// and biases when it needs a different number of them, as Maxout does
+func (nn *NeuralNetwork) setActivation(l int, activation Activation) {
+	nn.activations[l] = activation
+	width := activation.inputWidth(nn.layerSizes[l+1])
+	if rows, _ := nn.weights[l].Dims(); rows == width {
+		return
+	}
+	nn.weights[l] = mat.NewDense(width, nn.layerSizes[l], nil)
+	nn.init.fill(nn.weights[l], nn.rng)
+	nn.biases[l] = mat.NewDense(1, width, nil)
+	if nn.batchNorms[l] != nil {
+		nn.batchNorms[l] = newBatchNorm(width)
+	}
+}
+
+// copyBatchNorms returns deep copies of the batch normalization layers,
+// with nil for layers without one
+func (nn *NeuralNetwork) copyBatchNorms() []*batchNorm {
+	batchNorms := make([]*batchNorm, len(nn.batchNorms))
+	for l, bn := range nn.batchNorms {
+		if bn != nil {
+			batchNorms[l] = bn.clone()
+		}
+	}
+	return batchNorms
+}
+
+// SetLoss replaces the loss Train minimizes and reports. Networks start
+// out with MSE.
+func (nn *NeuralNetwork) SetLoss(loss Loss) {
+	nn.loss = loss
+}
+
+// SetShuffle controls whether TrainMiniBatch presents the samples in a new
+// random order every epoch. The caller's matrices are never reordered.
+func (nn *NeuralNetwork) SetShuffle(shuffle bool) {
+	nn.shuffle = shuffle
+}
+
+// SetL2 enables L2 weight decay: every update adds l2 * weight to the
+// weight gradient, so under SGD each step shrinks a weight by
+// learningRate * l2 * weight on top of the loss gradient. Raising the
+// learning rate therefore strengthens the decay too. Biases are not
+// decayed, and 0 disables it.
+func (nn *NeuralNetwork) SetL2(l2 float64) {
+	nn.l2 = l2
+}
+
+// SetL1 enables L1 regularization: every update adds l1 * sign(weight) to
+// the weight gradient, pulling weights toward zero by a constant amount so
+// that many end up at or near zero. It combines with SetL2 into an elastic
+// net penalty. Biases are not regularized, and 0 disables it.
+func (nn *NeuralNetwork) SetL1(l1 float64) {
+	nn.l1 = l1
+}
+
+// SetFrozen freezes the layers whose entry in frozen is true, one entry
+// per weight matrix with the input side first, and unfreezes the rest.
+// Frozen layers still take part in the forward pass and pass errors back
+// to earlier layers, but training never updates their weights, biases or
+// batch normalization scales and shifts. A nil frozen unfreezes every
+// layer.
+func (nn *NeuralNetwork) SetFrozen(frozen []bool) error {
+	if frozen == nil {
+		nn.frozen = nil
+		return nil
+	}
+	if len(frozen) != len(nn.weights) {
+		return fmt.Errorf("nngo: got %d frozen flags, network has %d layers", len(frozen), len(nn.weights))
+	}
+	nn.frozen = append([]bool(nil), frozen...)
+	return nil
+}
+
+// isFrozen reports whether layer l is excluded from updates
+func (nn *NeuralNetwork) isFrozen(l int) bool {
+	return nn.frozen != nil && nn.frozen[l]
+}
+
+// weightTie makes weights[decoder] the transpose of weights[encoder]
+type weightTie struct {
+	encoder, decoder int
+}
+
+// tieWeights ties decoder's weights to the transpose of encoder's and
+// copies them over
+func (nn *NeuralNetwork) tieWeights(encoder, decoder int) error {
+	numLayers := len(nn.weights)
+	if encoder < 0 || encoder >= numLayers || decoder < 0 || decoder >= numLayers || encoder == decoder {
+		return fmt.Errorf("nngo: cannot tie layers %d and %d of a network with %d layers", encoder, decoder, numLayers)
+	}
+	er, ec := nn.weights[encoder].Dims()
+	dr, dc := nn.weights[decoder].Dims()
+	if er != dc || ec != dr {
+		return fmt.Errorf("nngo: cannot tie %dx%d layer %d weights to the transpose of %dx%d layer %d weights",
+			dr, dc, decoder, er, ec, encoder)
+	}
+	for _, tie := range nn.ties {
+		if tie.decoder == decoder || tie.decoder == encoder || tie.encoder == decoder {
+			return fmt.Errorf("nngo: layers %d and %d are already tied", tie.encoder, tie.decoder)
+		}
+	}
+	nn.ties = append(nn.ties, weightTie{encoder: encoder, decoder: decoder})
+	nn.weights[decoder].Copy(nn.weights[encoder].T())
+	return nil
+}
+
+// isTiedDecoder reports whether layer l's weights follow another layer's
+func (nn *NeuralNetwork) isTiedDecoder(l int) bool {
+	for _, tie := range nn.ties {
+		if tie.decoder == l {
+			return true
+		}
+	}
+	return false
+}
+
+// syncTiedWeights copies the transpose of every encoder's weights into its
+// decoder
+func (nn *NeuralNetwork) syncTiedWeights() {
+	for _, tie := range nn.ties {
+		nn.weights[tie.decoder].Copy(nn.weights[tie.encoder].T())
+	}
+}
+
+// SetMaxGradNorm enables gradient clipping: whenever the L2 norm of all
+// weight and bias gradients of a step taken together exceeds maxGradNorm,
+// every gradient is scaled by maxGradNorm/norm before the update. A value
+// <= 0 disables clipping.
+func (nn *NeuralNetwork) SetMaxGradNorm(maxGradNorm float64) {
+	nn.maxGradNorm = maxGradNorm
+}
+
+// SetDropout enables inverted dropout on every hidden layer during
+// training: each hidden activation is zeroed with probability p and the
+// survivors are scaled by 1/(1-p). A new mask is drawn for every pass, and
+// Predict always uses the full network. 0 disables dropout.
+func (nn *NeuralNetwork) SetDropout(p float64) {
+	if p < 0 || p >= 1 {
+		panic(fmt.Sprintf("nngo: dropout probability %v outside [0, 1)", p))
+	}
+	nn.dropout = p
+}
+
+// trainStep runs one feedforward and backpropagation pass over inputs,
+// updates each layer with its entry of learningRates and returns the loss
+// before the update
+func (nn *NeuralNetwork) trainStep(inputs, targets *mat.Dense, learningRates []float64) float64 {
+	return nn.trainWeightedStep(inputs, targets, nil, learningRates)
+}
+
+// trainWeightedStep is trainStep with each sample's gradient scaled by its
+// entry of sampleWeights, unless that is nil
+func (nn *NeuralNetwork) trainWeightedStep(inputs, targets *mat.Dense, sampleWeights, learningRates []float64) float64 {
+	scratch := nn.trainScratch()
 
-		finalInput := mat.NewDense(0, 0, nil)
-		finalInput.Mul(nn.weightsHiddenOutput, hiddenOutput)
-		finalOutput := applyActivation(finalInput, sigmoid)
+	// Feedforward
+	pass := nn.feedforwardInto(&scratch.pass, inputs, trainMode)
+	predictions := pass.outputs[len(pass.outputs)-1]
 
-		// Backpropagation
-		outputErrors := mat.NewDense(0, 0, nil)
-		outputErrors.Sub(targets, finalOutput)
+	// Backpropagation
+	weightGradients, biasGradients := nn.backpropagate(pass, targets, sampleWeights, scratch)
 
-		outputGradient := applyActivationDerivative(finalOutput, sigmoidDerivative)
-		outputGradient.MulElem(outputGradient, outputErrors)
-		outputGradient.Scale(learningRate, outputGradient)
+	// Update weights and biases
+	nn.applyGradients(weightGradients, biasGradients, learningRates)
+	nn.applyBatchNormGradients(scratch.gammaGradients, scratch.betaGradients, learningRates)
 
-		hiddenErrors := mat.NewDense(0, 0, nil)
-		hiddenErrors.Mul(nn.weightsHiddenOutput.T(), outputErrors)
+	return nn.loss.Loss(predictions, targets)
+}
+
+// accumulateStep runs one feedforward and backpropagation pass over inputs
+// and adds the gradients to those accumulated in scratch, starting over
+// when first is set. It returns the loss without updating the network.
+func (nn *NeuralNetwork) accumulateStep(inputs, targets *mat.Dense, first bool) float64 {
+	scratch := nn.trainScratch()
+	pass := nn.feedforwardInto(&scratch.pass, inputs, trainMode)
+	predictions := pass.outputs[len(pass.outputs)-1]
+	weightGradients, biasGradients := nn.backpropagate(pass, targets, nil, scratch)
+
+	acc := &scratch.accumulated
+	accumulate(&acc.weightGradients, weightGradients, first)
+	accumulate(&acc.biasGradients, biasGradients, first)
+	accumulate(&acc.gammaGradients, scratch.gammaGradients, first)
+	accumulate(&acc.betaGradients, scratch.betaGradients, first)
+	return nn.loss.Loss(predictions, targets)
+}
+
+// applyAccumulated updates the network with the mean of the gradients of
+// the last steps calls to accumulateStep
+func (nn *NeuralNetwork) applyAccumulated(steps int, learningRates []float64) {
+	acc := &nn.trainScratch().accumulated
+	for _, gradients := range [][]*mat.Dense{acc.weightGradients, acc.biasGradients, acc.gammaGradients, acc.betaGradients} {
+		for _, g := range gradients {
+			if g != nil {
+				g.Scale(1/float64(steps), g)
+			}
+		}
+	}
+	nn.applyGradients(acc.weightGradients, acc.biasGradients, learningRates)
+	nn.applyBatchNormGradients(acc.gammaGradients, acc.betaGradients, learningRates)
+}
+
+// accumulate adds every non-nil gradient in src to the matching matrix of
+// *dst, or copies it there when first is set
+func accumulate(dst *[]*mat.Dense, src []*mat.Dense, first bool) {
+	grow(dst, len(src))
+	for l, g := range src {
+		if g == nil {
+			continue
+		}
+		r, c := g.Dims()
+		sum := reuse(&(*dst)[l], r, c)
+		if first {
+			sum.Copy(g)
+		} else {
+			sum.Add(sum, g)
+		}
+	}
+}
+
+// applyBatchNormGradients updates the batch normalization scales and shifts
+// of every unfrozen layer that has them
+func (nn *NeuralNetwork) applyBatchNormGradients(gammaGradients, betaGradients []*mat.Dense, learningRates []float64) {
+	for l, bn := range nn.batchNorms {
+		if bn != nil && !nn.isFrozen(l) {
+			nn.optimizer.Update(bn.gamma, gammaGradients[l], learningRates[l])
+			nn.optimizer.Update(bn.beta, betaGradients[l], learningRates[l])
+		}
+	}
+}
+
+// Gradients returns the gradient of the loss over inputs and targets with
+// respect to every weight and bias matrix, without touching the network.
+// The gradients are not scaled by any learning rate, and the network runs
+// as in Predict: without dropout and with running batch normalization
+// statistics. Batch normalization scales and shifts get no gradients here.
+// With target normalization, targets are standardized as in training, by
+// their own statistics if the network has not been trained yet; the
+// normalization is not fitted here.
+func (nn *NeuralNetwork) Gradients(inputs, targets *mat.Dense) (weightGradients, biasGradients []*mat.Dense, err error) {
+	if err := nn.CheckDims(inputs, targets); err != nil {
+		return nil, nil, err
+	}
+	targets = nn.learnedTargets(targets, false)
+	weightGradients, biasGradients = nn.backpropagate(nn.feedforward(inputs, evalMode), targets, nil, new(trainScratch))
+	return weightGradients, biasGradients, nil
+}
 
-		hiddenGradient := applyActivationDerivative(hiddenOutput, sigmoidDerivative)
-		hiddenGradient.MulElem(hiddenGradient, hiddenErrors)
-		hiddenGradient.Scale(learningRate, hiddenGradient)
+// ApplyGradients takes one optimizer step with the given gradients, shaped
+// like those returned by Gradients. Gradient clipping and the L1 and L2
+// penalties are applied as configured, and learningRate only scales the
+// final update. The caller's matrices are not modified.
+func (nn *NeuralNetwork) ApplyGradients(weightGradients, biasGradients []*mat.Dense, learningRate float64) error {
+	if len(weightGradients) != len(nn.weights) || len(biasGradients) != len(nn.biases) {
+		return fmt.Errorf("nngo: got %d weight and %d bias gradients, network has %d layers",
+			len(weightGradients), len(biasGradients), len(nn.weights))
+	}
+	weightCopies := make([]*mat.Dense, len(weightGradients))
+	biasCopies := make([]*mat.Dense, len(biasGradients))
+	for l := range nn.weights {
+		if err := sameShape(weightGradients[l], nn.weights[l]); err != nil {
+			return fmt.Errorf("nngo: layer %d weight gradient: %w", l+1, err)
+		}
+		if err := sameShape(biasGradients[l], nn.biases[l]); err != nil {
+			return fmt.Errorf("nngo: layer %d bias gradient: %w", l+1, err)
+		}
+		weightCopies[l] = mat.DenseCopyOf(weightGradients[l])
+		biasCopies[l] = mat.DenseCopyOf(biasGradients[l])
+	}
+	nn.applyGradients(weightCopies, biasCopies, nn.uniformRates(learningRate))
+	return nil
+}
 
-		// Update weights
-		hiddenOutputT := hiddenOutput.T()
-		deltaWeightsHO := mat.NewDense(0, 0, nil)
-		deltaWeightsHO.Mul(outputGradient, hiddenOutputT)
-		nn.weightsHiddenOutput.Add(nn.weightsHiddenOutput, deltaWeightsHO)
+// applyGradients clips the gradients, adds the L1 and L2 penalties and
+// passes them to the optimizer with each layer's learning rate. A tied
+// decoder's weight gradient is transposed onto its encoder's, which is
+// updated at the encoder's rate. The gradient matrices are modified in
+// place.
+func (nn *NeuralNetwork) applyGradients(weightGradients, biasGradients []*mat.Dense, learningRates []float64) {
+	if nn.maxGradNorm > 0 {
+		clipGradients(nn.maxGradNorm, weightGradients, biasGradients)
+	}
+	for _, tie := range nn.ties {
+		encoder := weightGradients[tie.encoder]
+		encoder.Add(encoder, weightGradients[tie.decoder].T())
+	}
+
+	scratch := nn.trainScratch()
+	grow(&scratch.decay, len(nn.weights))
+	for l := range nn.weights {
+		if nn.isFrozen(l) {
+			continue
+		}
+		nn.optimizer.Update(nn.biases[l], biasGradients[l], learningRates[l])
+		if nn.isTiedDecoder(l) {
+			continue
+		}
+		if nn.l2 != 0 {
+			r, c := nn.weights[l].Dims()
+			decay := reuse(&scratch.decay[l], r, c)
+			decay.Scale(nn.l2, nn.weights[l])
+			weightGradients[l].Add(weightGradients[l], decay)
+		}
+		if nn.l1 != 0 {
+			r, c := nn.weights[l].Dims()
+			decay := reuse(&scratch.decay[l], r, c)
+			decay.Apply(func(_, _ int, w float64) float64 {
+				switch {
+				case w > 0:
+					return nn.l1
+				case w < 0:
+					return -nn.l1
+				}
+				return 0
+			}, nn.weights[l])
+			weightGradients[l].Add(weightGradients[l], decay)
+		}
+		nn.optimizer.Update(nn.weights[l], weightGradients[l], learningRates[l])
+	}
+	nn.syncTiedWeights()
+}
 
-		inputsT := inputs.T()
-		deltaWeightsIH := mat.NewDense(0, 0, nil)
-		deltaWeightsIH.Mul(hiddenGradient, inputsT)
-		nn.weightsInputHidden.Add(nn.weightsInputHidden, deltaWeightsIH)
+// trainScratch returns the network's training scratch space, creating it
+// on first use
+func (nn *NeuralNetwork) trainScratch() *trainScratch {
+	if nn.scratch == nil {
+		nn.scratch = new(trainScratch)
 	}
+	return nn.scratch
 }
 
-func applyActivation(m *mat.Dense, activationFunc func(float64) float64) *mat.Dense {
+// sameShape reports an error unless m has want's dimensions
+func sameShape(m, want *mat.Dense) error {
+	r, c := m.Dims()
+	wr, wc := want.Dims()
+	if r != wr || c != wc {
+		return fmt.Errorf("is %dx%d, expected %dx%d", r, c, wr, wc)
+	}
+	return nil
+}
+
+// trainScratch holds the matrices a training step writes into, so steps
+// over equally sized batches reuse them instead of allocating new ones
+type trainScratch struct {
+	pass            forwardPass
+	weightGradients []*mat.Dense
+	biasGradients   []*mat.Dense
+	errors          []*mat.Dense
+	decay           []*mat.Dense
+	gammaGradients  []*mat.Dense
+	betaGradients   []*mat.Dense
+	// accumulated sums gradients over the micro-batches of one update
+	accumulated struct {
+		weightGradients, biasGradients []*mat.Dense
+		gammaGradients, betaGradients  []*mat.Dense
+	}
+}
+
+// reuse returns *m when it is already r x c and otherwise replaces it with
+// a new r x c matrix. The contents of a reused matrix are left as they were.
+func reuse(m **mat.Dense, r, c int) *mat.Dense {
+	if *m != nil {
+		if mr, mc := (*m).Dims(); mr == r && mc == c {
+			return *m
+		}
+	}
+	*m = mat.NewDense(r, c, nil)
+	return *m
+}
+
+// grow resizes *s to n entries, keeping existing ones
+func grow(s *[]*mat.Dense, n int) {
+	if len(*s) != n {
+		*s = append((*s)[:0:0], make([]*mat.Dense, n)...)
+	}
+}
+
+// backpropagate returns the gradient of the loss with respect to every
+// weight and bias matrix for the recorded feedforward pass, with each
+// sample's output error scaled by its entry of sampleWeights unless that is
+// nil. The gradients are written into scratch's matrices.
+func (nn *NeuralNetwork) backpropagate(pass *forwardPass, targets *mat.Dense, sampleWeights []float64, scratch *trainScratch) (weightGradients, biasGradients []*mat.Dense) {
+	last := len(nn.weights) - 1
+	predictions := pass.outputs[last+1]
+	rows, _ := predictions.Dims()
+
+	var delta *mat.Dense
+	if nn.activations[last].rowFunc != nil {
+		switch nn.loss.(type) {
+		case CategoricalCrossEntropy:
+			delta = softmaxCrossEntropyDelta(predictions, targets)
+		case SparseCategoricalCrossEntropy:
+			delta = softmaxSparseCrossEntropyDelta(predictions, targets, nn.labelSmoothing)
+		default:
+			panic(fmt.Sprintf("nngo: %s output layer requires the CategoricalCrossEntropy or SparseCategoricalCrossEntropy loss", nn.activations[last].Name))
+		}
+	} else {
+		outputErrors := nn.loss.Gradient(predictions, targets)
+		delta = nn.activations[last].delta(pass.preActivations[last], pass.activations[last+1], outputErrors, nn.deterministic)
+	}
+	for i, w := range sampleWeights {
+		row := delta.RawRowView(i)
+		for j := range row {
+			row[j] *= w
+		}
+	}
+
+	grow(&scratch.weightGradients, len(nn.weights))
+	grow(&scratch.biasGradients, len(nn.biases))
+	grow(&scratch.errors, len(nn.weights))
+	grow(&scratch.gammaGradients, len(nn.weights))
+	grow(&scratch.betaGradients, len(nn.weights))
+	for l := last; l >= 0; l-- {
+		if bn := nn.batchNorms[l]; bn != nil {
+			delta, scratch.gammaGradients[l], scratch.betaGradients[l] = bn.backward(delta, pass.batchNorms[l])
+		}
+
+		fanOut, fanIn := nn.weights[l].Dims()
+		reuse(&scratch.weightGradients[l], fanOut, fanIn).Mul(delta.T(), pass.outputs[l])
+		sumRowsInto(reuse(&scratch.biasGradients[l], 1, fanOut), delta)
+
+		// Propagate the error to the previous layer
+		if l > 0 {
+			prevErrors := reuse(&scratch.errors[l], rows, fanIn)
+			prevErrors.Mul(delta, nn.weights[l])
+			if mask := pass.masks[l]; mask != nil {
+				prevErrors.MulElem(prevErrors, mask)
+			}
+			delta = nn.activations[l-1].delta(pass.preActivations[l-1], pass.activations[l], prevErrors, nn.deterministic)
+		}
+	}
+
+	return scratch.weightGradients, scratch.biasGradients
+}
+
+// clipGradients rescales all gradients in place by maxNorm/norm when their
+// global L2 norm exceeds maxNorm
+func clipGradients(maxNorm float64, gradientSets ...[]*mat.Dense) {
+	sumSquares := 0.0
+	for _, gradients := range gradientSets {
+		for _, g := range gradients {
+			norm := mat.Norm(g, 2)
+			sumSquares += norm * norm
+		}
+	}
+	norm := math.Sqrt(sumSquares)
+	if norm <= maxNorm {
+		return
+	}
+	for _, gradients := range gradientSets {
+		for _, g := range gradients {
+			g.Scale(maxNorm/norm, g)
+		}
+	}
+}
+
+// Predictor is a model that maps inputs, one sample per row, to outputs,
+// one row per sample. *NeuralNetwork and Ensemble implement it.
+type Predictor interface {
+	Predict(inputs *mat.Dense) *mat.Dense
+}
+
+var _ Predictor = (*NeuralNetwork)(nil)
+
+// Predict runs the feedforward pass on inputs (one sample per row) and
+// returns the network output for each sample. Dropout is never applied and
+// batch normalization uses the running statistics gathered in training.
+// Any output transform set by WithOutputTransform is applied last.
+// Predict panics with the error from CheckDims when inputs has the wrong
+// number of features; call CheckDims first to handle it gracefully.
+func (nn *NeuralNetwork) Predict(inputs *mat.Dense) *mat.Dense {
+	outputs := nn.predict(inputs)
+	if nn.outputTransform != nil {
+		outputs = nn.outputTransform(outputs)
+	}
+	return outputs
+}
+
+// predict is Predict without the output transform, giving outputs in the
+// units of the training targets
+func (nn *NeuralNetwork) predict(inputs *mat.Dense) *mat.Dense {
+	if err := nn.CheckDims(inputs, nil); err != nil {
+		panic(err)
+	}
+	pass := nn.feedforward(inputs, evalMode)
+	outputs := pass.outputs[len(pass.outputs)-1]
+	if nn.targetMean != nil {
+		outputs = denormalizeTargets(outputs, nn.targetMean, nn.targetStd)
+	}
+	return outputs
+}
+
+// trainingTargets returns targets as the network learns them: smoothed
+// with label smoothing, or standardized with target normalization. The
+// first call fits the normalization to targets; later calls reuse it.
+// SparseCategoricalCrossEntropy labels are returned unchanged, their
+// smoothing being applied to the gradient by backpropagate instead.
+func (nn *NeuralNetwork) trainingTargets(targets *mat.Dense) *mat.Dense {
+	return nn.learnedTargets(targets, true)
+}
+
+// learnedTargets is trainingTargets, fitting the target normalization only
+// if fit is set. Otherwise a network whose normalization is not fitted yet
+// standardizes targets by their own statistics and stays unfitted, so that
+// Predict is unchanged.
+func (nn *NeuralNetwork) learnedTargets(targets *mat.Dense, fit bool) *mat.Dense {
+	if _, ok := nn.loss.(SparseCategoricalCrossEntropy); ok {
+		return targets
+	}
+	if nn.labelSmoothing > 0 {
+		targets = SmoothLabels(targets, nn.labelSmoothing)
+	}
+	if !nn.normalizeTargets {
+		return targets
+	}
+	if nn.targetMean == nil {
+		standardized, mean, std := Standardize(targets)
+		if fit {
+			nn.targetMean, nn.targetStd = mean, std
+		}
+		return standardized
+	}
+	return ApplyStandardize(targets, nn.targetMean, nn.targetStd)
+}
+
+// denormalizeTargets undoes ApplyStandardize with the same mean and std
+func denormalizeTargets(m *mat.Dense, mean, std []float64) *mat.Dense {
 	r, c := m.Dims()
 	result := mat.NewDense(r, c, nil)
 	for i := 0; i < r; i++ {
 		for j := 0; j < c; j++ {
-			result.Set(i, j, activationFunc(m.At(i, j)))
+			result.Set(i, j, m.At(i, j)*std[j]+mean[j])
+		}
+	}
+	return result
+}
+
+// checkTrainingData is CheckDims followed, when input validation is
+// enabled, by ValidateInputs
+func (nn *NeuralNetwork) checkTrainingData(inputs, targets *mat.Dense) error {
+	if err := nn.CheckDims(inputs, targets); err != nil {
+		return err
+	}
+	if nn.validateInputs {
+		return ValidateInputs(inputs, targets)
+	}
+	return nil
+}
+
+// CheckDims reports whether inputs and targets fit the network: inputs
+// must have one column per input unit, and targets, unless nil, one column
+// per output unit and a row for every input row. Targets of
+// SparseCategoricalCrossEntropy are a single column of whole-number class
+// labels, which target normalization cannot apply to and which label
+// smoothing needs a SoftmaxOutput layer for. With targets, a SoftmaxOutput
+// layer must be paired with CategoricalCrossEntropy or
+// SparseCategoricalCrossEntropy.
+func (nn *NeuralNetwork) CheckDims(inputs, targets *mat.Dense) error {
+	inRows, inCols := inputs.Dims()
+	if want := nn.layerSizes[0]; inCols != want {
+		return fmt.Errorf("nngo: input has %d features, network expects %d", inCols, want)
+	}
+	if targets == nil {
+		return nil
+	}
+	output := nn.activations[len(nn.activations)-1]
+	_, sparse := nn.loss.(SparseCategoricalCrossEntropy)
+	if _, categorical := nn.loss.(CategoricalCrossEntropy); output.rowFunc != nil && !categorical && !sparse {
+		return fmt.Errorf("nngo: %s output layer requires the CategoricalCrossEntropy or SparseCategoricalCrossEntropy loss", output.Name)
+	}
+	tgtRows, tgtCols := targets.Dims()
+	outputs := nn.layerSizes[len(nn.layerSizes)-1]
+	want := outputs
+	if sparse {
+		// One class label per sample
+		want = 1
+		if nn.normalizeTargets {
+			return errors.New("nngo: target normalization cannot be used with SparseCategoricalCrossEntropy class labels")
+		}
+		if nn.labelSmoothing > 0 && output.rowFunc == nil {
+			return errors.New("nngo: label smoothing with SparseCategoricalCrossEntropy requires a SoftmaxOutput layer")
+		}
+	}
+	if tgtCols != want {
+		return fmt.Errorf("nngo: target has %d outputs, network expects %d", tgtCols, want)
+	}
+	if tgtRows != inRows {
+		return fmt.Errorf("nngo: input has %d samples but target has %d", inRows, tgtRows)
+	}
+	if sparse {
+		for i := 0; i < tgtRows; i++ {
+			if v := targets.At(i, 0); v != math.Trunc(v) || v < 0 || v >= float64(outputs) {
+				return fmt.Errorf("nngo: label %v at row %d is not a class in [0, %d)", v, i, outputs)
+			}
+		}
+	}
+	return nil
+}
+
+// forwardPass records the intermediate values of one feedforward pass
+// for backpropagation. Index 0 of activations, outputs and masks is the
+// network input; index l+1 belongs to the layer computed by weights[l].
+type forwardPass struct {
+	// preActivations[l] is the input to activations[l+1]'s activation
+	preActivations []*mat.Dense
+	// activations[l] is layer l's activated output before dropout
+	activations []*mat.Dense
+	// outputs[l] is what layer l feeds forward, after dropout
+	outputs []*mat.Dense
+	// masks[l] is the inverted dropout mask applied to layer l, or nil
+	masks []*mat.Dense
+	// batchNorms[l] is the batch normalization record of weights[l]'s
+	// layer, or nil
+	batchNorms []*batchNormCache
+}
+
+// mode selects how the layers that behave differently in training and at
+// inference run during a forward pass
+type mode int
+
+const (
+	// evalMode runs the network as Predict does: no dropout, and batch
+	// normalization with the running statistics
+	evalMode mode = iota
+	// trainMode applies inverted dropout to the hidden layers' outputs and
+	// normalizes with, and accumulates, each batch's statistics
+	trainMode
+)
+
+// feedforward runs inputs through every layer in the given mode
+func (nn *NeuralNetwork) feedforward(inputs *mat.Dense, m mode) *forwardPass {
+	return nn.feedforwardInto(new(forwardPass), inputs, m)
+}
+
+// feedforwardInto is feedforward recording the pass into pass, reusing
+// its matrices where their shapes still fit
+func (nn *NeuralNetwork) feedforwardInto(pass *forwardPass, inputs *mat.Dense, m mode) *forwardPass {
+	numLayers := len(nn.weights)
+	if len(pass.batchNorms) != numLayers {
+		pass.batchNorms = make([]*batchNormCache, numLayers)
+	}
+	dropout := 0.0
+	if m == trainMode {
+		dropout = nn.dropout
+	}
+	grow(&pass.preActivations, numLayers)
+	grow(&pass.activations, numLayers+1)
+	grow(&pass.outputs, numLayers+1)
+	grow(&pass.masks, numLayers+1)
+	pass.activations[0] = inputs
+	pass.outputs[0] = inputs
+	rows, _ := inputs.Dims()
+
+	for l, w := range nn.weights {
+		width, _ := w.Dims()
+		z := reuse(&pass.preActivations[l], rows, width)
+		z.Mul(pass.outputs[l], w.T())
+		addBias(z, nn.biases[l])
+		if bn := nn.batchNorms[l]; bn != nil {
+			z, pass.batchNorms[l] = bn.forward(z, m)
+			pass.preActivations[l] = z
+		}
+
+		a := nn.activations[l].applyInto(&pass.activations[l+1], z, nn.deterministic)
+		_, units := a.Dims()
+
+		if dropout > 0 && l < numLayers-1 {
+			if pass.outputs[l+1] == a {
+				pass.outputs[l+1] = nil
+			}
+			mask := reuse(&pass.masks[l+1], rows, units)
+			fillDropoutMask(mask, dropout, nn.rng)
+			reuse(&pass.outputs[l+1], rows, units).MulElem(a, mask)
+		} else {
+			pass.masks[l+1] = nil
+			pass.outputs[l+1] = a
+		}
+	}
+
+	return pass
+}
+
+// fillDropoutMask overwrites mask with a fresh inverted dropout mask:
+// each entry is 0 with probability p and 1/(1-p) otherwise
+func fillDropoutMask(mask *mat.Dense, p float64, rng *rand.Rand) {
+	r, c := mask.Dims()
+	keep := 1.0 / (1.0 - p)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if rng.Float64() >= p {
+				mask.Set(i, j, keep)
+			} else {
+				mask.Set(i, j, 0)
+			}
 		}
 	}
+}
+
+// shuffleRows returns copies of inputs and targets with their rows
+// reordered by the same random permutation, so sample pairs stay aligned
+func shuffleRows(inputs, targets *mat.Dense, rng *rand.Rand) (*mat.Dense, *mat.Dense) {
+	rows, _ := inputs.Dims()
+	perm := permutation(rows, rng)
+	return selectRows(inputs, perm), selectRows(targets, perm)
+}
+
+// permutation returns a Fisher-Yates shuffle of 0..n-1
+func permutation(n int, rng *rand.Rand) []int {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j := rng.Intn(i + 1)
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm
+}
+
+// selectRows returns a new matrix made of m's rows at the given indices
+func selectRows(m *mat.Dense, indices []int) *mat.Dense {
+	_, c := m.Dims()
+	result := mat.NewDense(len(indices), c, nil)
+	for i, idx := range indices {
+		result.SetRow(i, m.RawRowView(idx))
+	}
 	return result
 }
 
-func applyActivationDerivative(m *mat.Dense, activationDerivativeFunc func(float64) float64) *mat.Dense {
+// addBias adds the 1 x c bias row to every row of m in place
+func addBias(m, bias *mat.Dense) {
 	r, c := m.Dims()
-	result := mat.NewDense(r, c, nil)
 	for i := 0; i < r; i++ {
 		for j := 0; j < c; j++ {
-			result.Set(i, j, activationDerivativeFunc(m.At(i, j)))
+			m.Set(i, j, m.At(i, j)+bias.At(0, j))
+		}
+	}
+}
+
+// sumRowsInto overwrites the 1 x c matrix dst with the column sums of m
+func sumRowsInto(dst, m *mat.Dense) {
+	r, c := m.Dims()
+	for j := 0; j < c; j++ {
+		sum := 0.0
+		for i := 0; i < r; i++ {
+			sum += m.At(i, j)
 		}
+		dst.Set(0, j, sum)
 	}
+}
+
+// meanSquare returns the mean of the squared elements of m
+func meanSquare(m *mat.Dense) float64 {
+	r, c := m.Dims()
+	sum := 0.0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			v := m.At(i, j)
+			sum += v * v
+		}
+	}
+	return sum / float64(r*c)
+}
+
+func applyActivationDerivative(m *mat.Dense, activationDerivativeFunc func(float64) float64, serial bool) *mat.Dense {
+	r, c := m.Dims()
+	result := mat.NewDense(r, c, nil)
+	applyElementwise(result, m, activationDerivativeFunc, serial)
 	return result
 }
 
@@ -134,10 +1360,15 @@ func main() {
 	})
 
 	// Create neural network
-	nn := NewNeuralNetwork(2, 2, 1)
+	nn := NewNeuralNetwork([]int{2, 2, 1})
 
 	// Train the neural network
-	nn.Train(inputs, targets, 10000, 0.1)
+	history, err := nn.Train(inputs, targets, 10000, 0.5)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Final loss: %v\n", history[len(history)-1])
 
 	// Test the neural network
 	testInputs := mat.NewDense(4, 2, []float64{
@@ -147,13 +1378,7 @@ func main() {
 		1, 1,
 	})
 
-	hiddenInput := mat.NewDense(0, 0, nil)
-	hiddenInput.Mul(nn.weightsInputHidden, testInputs)
-	hiddenOutput := applyActivation(hiddenInput, sigmoid)
-
-	finalInput := mat.NewDense(0, 0, nil)
-	finalInput.Mul(nn.weightsHiddenOutput, hiddenOutput)
-	finalOutput := applyActivation(finalInput, sigmoid)
+	finalOutput := nn.Predict(testInputs)
 
 	fmt.Println("Predictions:")
 	for i := 0; i < 4; i++ {
@@ -0,0 +1,797 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// xorData returns the four XOR samples and their targets
+func xorData() (inputs, targets *mat.Dense) {
+	inputs = mat.NewDense(4, 2, []float64{
+		0, 0,
+		0, 1,
+		1, 0,
+		1, 1,
+	})
+	targets = mat.NewDense(4, 1, []float64{0, 1, 1, 0})
+	return inputs, targets
+}
+
+// randomDense returns an r x c matrix of values drawn uniformly from
+// [0, 1) by a generator seeded with seed
+func randomDense(r, c int, seed int64) *mat.Dense {
+	rng := rand.New(rand.NewSource(seed))
+	data := make([]float64, r*c)
+	for i := range data {
+		data[i] = rng.Float64()
+	}
+	return mat.NewDense(r, c, data)
+}
+
+func TestTrainManySamples(t *testing.T) {
+	inputs := randomDense(100, 3, 1)
+	targets := mat.NewDense(100, 1, nil)
+	for i := 0; i < 100; i++ {
+		if inputs.At(i, 0)+inputs.At(i, 1) > 1 {
+			targets.Set(i, 0, 1)
+		}
+	}
+	nn := NewNeuralNetworkWithSeed([]int{3, 4, 1}, 1)
+	history, err := nn.Train(inputs, targets, 50, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 50 {
+		t.Fatalf("got %d losses, want 50", len(history))
+	}
+	if r, c := nn.Predict(inputs).Dims(); r != 100 || c != 1 {
+		t.Errorf("predictions are %dx%d, want 100x1", r, c)
+	}
+}
+
+func TestPredictWrongFeatures(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 2, 1}, 1)
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok {
+			t.Fatalf("Predict did not panic with an error, got %v", r)
+		}
+		if want := "nngo: input has 3 features, network expects 2"; err.Error() != want {
+			t.Errorf("panic %q, want %q", err, want)
+		}
+	}()
+	nn.Predict(mat.NewDense(1, 3, nil))
+}
+
+func TestSingleNeuronLearnsThreshold(t *testing.T) {
+	// Separating x < 1.5 from x > 1.5 needs a bias: without one the
+	// output at x = 0 is stuck at sigmoid(0) = 0.5
+	inputs := mat.NewDense(4, 1, []float64{0, 1, 2, 3})
+	targets := mat.NewDense(4, 1, []float64{0, 0, 1, 1})
+	nn := NewNeuralNetworkWithSeed([]int{1, 1}, 1)
+	if _, err := nn.Train(inputs, targets, 5000, 1); err != nil {
+		t.Fatal(err)
+	}
+	predictions := nn.Predict(inputs)
+	for i := 0; i < 4; i++ {
+		if got, want := math.Round(predictions.At(i, 0)), targets.At(i, 0); got != want {
+			t.Errorf("x = %v predicted %v, want %v", inputs.At(i, 0), predictions.At(i, 0), want)
+		}
+	}
+}
+
+func TestThreeHiddenLayers(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 4, 4, 4, 1}, 1)
+	if got := len(nn.Weights()); got != 4 {
+		t.Fatalf("got %d weight matrices, want 4", got)
+	}
+	history, err := nn.Train(inputs, targets, 5000, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, last := history[0], history[len(history)-1]; last >= first {
+		t.Errorf("loss went from %v to %v, want it to drop", first, last)
+	}
+	if r, c := nn.Predict(inputs).Dims(); r != 4 || c != 1 {
+		t.Errorf("predictions are %dx%d, want 4x1", r, c)
+	}
+}
+
+func TestTrainHistoryDecreases(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 2, 1}, 1)
+	history, err := nn.Train(inputs, targets, 10000, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 10000 {
+		t.Fatalf("got %d losses, want 10000", len(history))
+	}
+	for i := 1000; i < len(history); i++ {
+		if history[i] > history[i-1] {
+			t.Fatalf("loss rose from %v to %v at epoch %d", history[i-1], history[i], i)
+		}
+	}
+}
+
+func TestTrainMiniBatch(t *testing.T) {
+	// 1000 samples split into 31 full batches of 32 and a final 8
+	inputs := randomDense(1000, 2, 1)
+	targets := mat.NewDense(1000, 1, nil)
+	for i := 0; i < 1000; i++ {
+		if inputs.At(i, 0) > inputs.At(i, 1) {
+			targets.Set(i, 0, 1)
+		}
+	}
+	nn := NewNeuralNetworkWithSeed([]int{2, 4, 1}, 1)
+	history, err := nn.TrainMiniBatch(inputs, targets, 20, 32, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 20 {
+		t.Fatalf("got %d losses, want 20", len(history))
+	}
+	if first, last := history[0], history[len(history)-1]; last >= first {
+		t.Errorf("loss went from %v to %v, want it to drop", first, last)
+	}
+	if _, err := nn.TrainMiniBatch(inputs, targets, 1, 0, 0.5); err == nil {
+		t.Error("batch size 0 gave no error")
+	}
+}
+
+func TestShuffleRows(t *testing.T) {
+	inputs := mat.NewDense(10, 2, nil)
+	targets := mat.NewDense(10, 1, nil)
+	for i := 0; i < 10; i++ {
+		inputs.SetRow(i, []float64{float64(i), float64(10 * i)})
+		targets.Set(i, 0, float64(100*i))
+	}
+	original := mat.DenseCopyOf(inputs)
+
+	in1, tgt1 := shuffleRows(inputs, targets, rand.New(rand.NewSource(7)))
+	in2, tgt2 := shuffleRows(inputs, targets, rand.New(rand.NewSource(7)))
+	if !mat.Equal(in1, in2) || !mat.Equal(tgt1, tgt2) {
+		t.Error("equal seeds gave different shuffles")
+	}
+	if mat.Equal(in1, inputs) {
+		t.Error("shuffle left every row in place")
+	}
+	if !mat.Equal(inputs, original) {
+		t.Error("shuffle modified the caller's inputs")
+	}
+	seen := make(map[float64]bool)
+	for i := 0; i < 10; i++ {
+		x := in1.At(i, 0)
+		if in1.At(i, 1) != 10*x || tgt1.At(i, 0) != 100*x {
+			t.Errorf("row %d split sample %v from its columns or target", i, x)
+		}
+		seen[x] = true
+	}
+	if len(seen) != 10 {
+		t.Errorf("shuffle kept %d of 10 samples", len(seen))
+	}
+}
+
+func TestL2ShrinksWeights(t *testing.T) {
+	// Few noisy samples and many hidden units invite large weights
+	inputs := randomDense(8, 3, 1)
+	targets := randomDense(8, 1, 2)
+	weightNorm := func(l2 float64) float64 {
+		nn := New([]int{3, 16, 1}, WithSeed(1), WithInit(GlorotUniform))
+		nn.SetL2(l2)
+		if _, err := nn.Train(inputs, targets, 3000, 0.5); err != nil {
+			t.Fatal(err)
+		}
+		norm := 0.0
+		for _, w := range nn.Weights() {
+			norm += mat.Norm(w, 2)
+		}
+		return norm
+	}
+	plain, decayed := weightNorm(0), weightNorm(1e-3)
+	if decayed >= plain {
+		t.Errorf("weight norm %v with L2, %v without", decayed, plain)
+	}
+}
+
+func TestDropoutOnlyInTraining(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 8, 1}, 1)
+	nn.SetDropout(0.5)
+	if _, err := nn.Train(inputs, targets, 200, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	first := nn.Predict(inputs)
+	for i := 0; i < 5; i++ {
+		if got := nn.Predict(inputs); !mat.Equal(got, first) {
+			t.Fatalf("Predict changed between calls: %v, then %v", first.RawMatrix().Data, got.RawMatrix().Data)
+		}
+	}
+
+	mask := mat.NewDense(100, 100, nil)
+	fillDropoutMask(mask, 0.5, rand.New(rand.NewSource(1)))
+	dropped := 0
+	for _, v := range mask.RawMatrix().Data {
+		switch v {
+		case 0:
+			dropped++
+		case 2:
+		default:
+			t.Fatalf("mask entry %v, want 0 or 1/(1-p) = 2", v)
+		}
+	}
+	if dropped < 4500 || dropped > 5500 {
+		t.Errorf("dropped %d of 10000 units at p = 0.5", dropped)
+	}
+}
+
+func TestEarlyStopping(t *testing.T) {
+	// Random targets cannot generalize, so the validation loss soon
+	// stops improving while the training loss keeps falling
+	trainIn, trainTgt := randomDense(10, 3, 1), randomDense(10, 1, 2)
+	valIn, valTgt := randomDense(10, 3, 3), randomDense(10, 1, 4)
+	nn := New([]int{3, 32, 1}, WithSeed(1), WithInit(GlorotUniform))
+	history, err := nn.TrainWithValidation(trainIn, trainTgt, valIn, valTgt, 20000, 0.5, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) == 20000 {
+		t.Fatal("training ran all 20000 epochs")
+	}
+	best := history[0]
+	for _, loss := range history {
+		best = math.Min(best, loss)
+	}
+	if got := (MSE{}).Loss(nn.Predict(valIn), valTgt); got != best {
+		t.Errorf("restored weights have validation loss %v, want the best %v", got, best)
+	}
+}
+
+func TestGradientClipping(t *testing.T) {
+	inputs, _ := xorData()
+	targets := mat.NewDense(4, 1, []float64{1e10, 1e10, 1e10, 1e10})
+	finite := func(maxGradNorm float64) bool {
+		nn := New([]int{2, 4, 1}, WithSeed(1), WithActivation(Linear), WithOutputActivation(Linear))
+		nn.SetMaxGradNorm(maxGradNorm)
+		if _, err := nn.Train(inputs, targets, 50, 0.1); err != nil {
+			t.Fatal(err)
+		}
+		return nn.checkFinite() == nil
+	}
+	if finite(0) {
+		t.Fatal("unclipped training on huge errors kept finite weights")
+	}
+	if !finite(1) {
+		t.Error("clipped training produced non-finite weights")
+	}
+}
+
+// applyLoops is the hand-rolled nested loop the activation helpers used
+// before switching to Dense.Apply, kept as a benchmark baseline
+func applyLoops(m *mat.Dense, f func(float64) float64) *mat.Dense {
+	r, c := m.Dims()
+	result := mat.NewDense(r, c, nil)
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			result.Set(i, j, f(m.At(i, j)))
+		}
+	}
+	return result
+}
+
+func TestApplyMatchesLoops(t *testing.T) {
+	m := randomDense(50, 40, 1)
+	if got, want := applyActivationDerivative(m, sigmoidDerivative, true), applyLoops(m, sigmoidDerivative); !mat.Equal(got, want) {
+		t.Error("Dense.Apply result differs from the nested loops")
+	}
+}
+
+func BenchmarkActivationLoops(b *testing.B) {
+	m := randomDense(1000, 1000, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		applyLoops(m, sigmoid)
+	}
+}
+
+func BenchmarkActivationApply(b *testing.B) {
+	m := randomDense(1000, 1000, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		applyActivationDerivative(m, sigmoid, true)
+	}
+}
+
+func TestScratchReuseKeepsWeights(t *testing.T) {
+	inputs, targets := xorData()
+	reused := NewNeuralNetworkWithSeed([]int{2, 4, 1}, 1)
+	fresh := NewNeuralNetworkWithSeed([]int{2, 4, 1}, 1)
+	rates := reused.uniformRates(0.5)
+	for epoch := 0; epoch < 100; epoch++ {
+		reused.trainStep(inputs, targets, rates)
+		// Dropping the scratch space makes every step allocate anew,
+		// as training did before the matrices were reused
+		fresh.scratch = nil
+		fresh.trainStep(inputs, targets, rates)
+	}
+	for l, w := range reused.Weights() {
+		if !mat.Equal(w, fresh.Weights()[l]) {
+			t.Errorf("layer %d weights differ when scratch matrices are reused", l)
+		}
+	}
+}
+
+// benchmarkTrainEpoch measures one full-batch epoch on 256 samples,
+// discarding the scratch space before each epoch when fresh is set
+func benchmarkTrainEpoch(b *testing.B, fresh bool) {
+	inputs, targets := randomDense(256, 8, 1), randomDense(256, 2, 2)
+	nn := NewNeuralNetworkWithSeed([]int{8, 32, 32, 2}, 1)
+	rates := nn.uniformRates(0.1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if fresh {
+			nn.scratch = nil
+		}
+		nn.trainStep(inputs, targets, rates)
+	}
+}
+
+func BenchmarkTrainEpoch(b *testing.B) {
+	benchmarkTrainEpoch(b, false)
+}
+
+func BenchmarkTrainEpochFreshScratch(b *testing.B) {
+	benchmarkTrainEpoch(b, true)
+}
+
+func TestTrainDimensionErrors(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	for _, tc := range []struct {
+		name            string
+		inputs, targets *mat.Dense
+		want            string
+	}{
+		{"features", mat.NewDense(4, 3, nil), mat.NewDense(4, 1, nil), "nngo: input has 3 features, network expects 2"},
+		{"outputs", mat.NewDense(4, 2, nil), mat.NewDense(4, 2, nil), "nngo: target has 2 outputs, network expects 1"},
+		{"samples", mat.NewDense(4, 2, nil), mat.NewDense(3, 1, nil), "nngo: input has 4 samples but target has 3"},
+	} {
+		_, err := nn.Train(tc.inputs, tc.targets, 1, 0.1)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("%s mismatch: error %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestTrainContextTimeout(t *testing.T) {
+	inputs, targets := randomDense(500, 10, 1), randomDense(500, 1, 2)
+	nn := NewNeuralNetworkWithSeed([]int{10, 64, 1}, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	history, err := nn.TrainContext(ctx, inputs, targets, 1000000, 0.1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := len(history); n == 0 || n == 1000000 {
+		t.Errorf("history has %d epochs, want some but not all", n)
+	}
+}
+
+func TestLearningRateScalesDelta(t *testing.T) {
+	inputs, targets := xorData()
+	deltas := func(learningRate float64) []*mat.Dense {
+		nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+		before := nn.Weights()
+		if _, err := nn.Train(inputs, targets, 1, learningRate); err != nil {
+			t.Fatal(err)
+		}
+		after := nn.Weights()
+		for l := range after {
+			after[l].Sub(after[l], before[l])
+		}
+		return after
+	}
+	single, double := deltas(0.1), deltas(0.2)
+	for l := range single {
+		single[l].Scale(2, single[l])
+		if !mat.EqualApprox(double[l], single[l], 1e-12) {
+			t.Errorf("layer %d: doubled learning rate gave delta %v, want %v", l, double[l].RawMatrix().Data, single[l].RawMatrix().Data)
+		}
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	weights := nn.Weights()
+	predictions := nn.Predict(inputs)
+
+	clone := nn.Clone()
+	if _, err := clone.Train(inputs, targets, 100, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	for l, w := range nn.Weights() {
+		if !mat.Equal(w, weights[l]) {
+			t.Errorf("training the clone changed layer %d of the original", l)
+		}
+	}
+	if !mat.Equal(nn.Predict(inputs), predictions) {
+		t.Error("training the clone changed the original's predictions")
+	}
+}
+
+func TestCloneKeepsRandomStream(t *testing.T) {
+	inputs, targets := xorData()
+	twin := func() *NeuralNetwork {
+		nn := NewNeuralNetworkWithSeed([]int{2, 4, 1}, 1)
+		nn.SetDropout(0.3)
+		nn.SetShuffle(true)
+		return nn
+	}
+	cloned, untouched := twin(), twin()
+	clone := cloned.Clone()
+	for _, nn := range []*NeuralNetwork{clone, cloned, untouched} {
+		if _, err := nn.TrainMiniBatch(inputs, targets, 20, 2, 0.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for l, w := range cloned.Weights() {
+		if !mat.Equal(w, untouched.Weights()[l]) {
+			t.Errorf("cloning changed the original's training of layer %d", l)
+		}
+		if !mat.Equal(w, clone.Weights()[l]) {
+			t.Errorf("the clone trained layer %d differently from the original", l)
+		}
+	}
+}
+
+func TestSetWeights(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	weights := nn.Weights()
+	weights[0].Set(0, 0, 42)
+	if nn.Weights()[0].At(0, 0) == 42 {
+		t.Fatal("Weights returned the network's own matrix")
+	}
+	if err := nn.SetWeights(weights); err != nil {
+		t.Fatal(err)
+	}
+	if got := nn.Weights()[0].At(0, 0); got != 42 {
+		t.Errorf("weight after SetWeights %v, want 42", got)
+	}
+
+	before := nn.Weights()
+	wrong := []*mat.Dense{mat.NewDense(3, 2, nil), mat.NewDense(2, 3, nil)}
+	if err := nn.SetWeights(wrong); err == nil {
+		t.Error("SetWeights accepted a wrongly sized matrix")
+	}
+	if err := nn.SetWeights(before[:1]); err == nil {
+		t.Error("SetWeights accepted too few matrices")
+	}
+	for l, w := range nn.Weights() {
+		if !mat.Equal(w, before[l]) {
+			t.Errorf("rejected SetWeights changed layer %d", l)
+		}
+	}
+}
+
+func TestL1SparsifiesWeights(t *testing.T) {
+	// Only the first of five features predicts the noisy target, but
+	// with 20 samples the others pick up spurious weight
+	inputs, noise := randomDense(20, 5, 1), randomDense(20, 1, 2)
+	targets := mat.NewDense(20, 1, nil)
+	for i := 0; i < 20; i++ {
+		targets.Set(i, 0, 2*inputs.At(i, 0)+0.5*noise.At(i, 0))
+	}
+	nearZero := func(l1 float64) int {
+		nn := New([]int{5, 1}, WithSeed(1), WithInit(GlorotUniform), WithOutputActivation(Linear))
+		nn.SetL1(l1)
+		if _, err := nn.Train(inputs, targets, 3000, 0.1); err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, w := range nn.Weights()[0].RawMatrix().Data {
+			if math.Abs(w) < 0.01 {
+				count++
+			}
+		}
+		return count
+	}
+	plain, sparse := nearZero(0), nearZero(0.01)
+	if sparse <= plain {
+		t.Errorf("%d near-zero weights with L1, %d without", sparse, plain)
+	}
+}
+
+func TestFrozenLayers(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	if err := nn.SetFrozen([]bool{true}); err == nil {
+		t.Error("SetFrozen accepted one flag for two layers")
+	}
+	if err := nn.SetFrozen([]bool{true, false}); err != nil {
+		t.Fatal(err)
+	}
+	before := nn.Weights()
+	if _, err := nn.Train(inputs, targets, 100, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	after := nn.Weights()
+	if !mat.Equal(after[0], before[0]) {
+		t.Error("frozen input-hidden weights changed")
+	}
+	if mat.Equal(after[1], before[1]) {
+		t.Error("unfrozen output weights did not change")
+	}
+}
+
+func TestLinearOutputRegression(t *testing.T) {
+	inputs := mat.NewDense(21, 1, nil)
+	targets := mat.NewDense(21, 1, nil)
+	for i := 0; i < 21; i++ {
+		x := -1 + 0.1*float64(i)
+		inputs.Set(i, 0, x)
+		targets.Set(i, 0, 2*x+3)
+	}
+	nn := New([]int{1, 1}, WithSeed(1), WithOutputActivation(Linear))
+	if _, err := nn.Train(inputs, targets, 2000, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if mse := (MSE{}).Loss(nn.Predict(inputs), targets); mse > 1e-6 {
+		t.Errorf("MSE %v fitting y = 2x + 3, want below 1e-6", mse)
+	}
+	if got := nn.Predict(mat.NewDense(1, 1, []float64{5})).At(0, 0); math.Abs(got-13) > 0.01 {
+		t.Errorf("prediction at x = 5 is %v, want 13", got)
+	}
+}
+
+func TestTrainingAndEvalModes(t *testing.T) {
+	inputs, _ := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 16, 1}, 1)
+	nn.SetDropout(0.5)
+	output := func(m mode) *mat.Dense {
+		pass := nn.feedforward(inputs, m)
+		return mat.DenseCopyOf(pass.outputs[len(pass.outputs)-1])
+	}
+	if !mat.Equal(output(evalMode), output(evalMode)) {
+		t.Error("eval-mode passes differ with dropout enabled")
+	}
+	if mat.Equal(output(trainMode), output(trainMode)) {
+		t.Error("training-mode passes are identical with dropout enabled")
+	}
+	if !mat.Equal(nn.Predict(inputs), output(evalMode)) {
+		t.Error("Predict does not run in eval mode")
+	}
+}
+
+func TestTrainLayerRates(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 3, 1}, 1)
+	before := nn.Weights()
+	rates := []float64{0.5, 0, 0.5}
+	if _, err := nn.TrainLayerRates(inputs, targets, 50, rates); err != nil {
+		t.Fatal(err)
+	}
+	for l, w := range nn.Weights() {
+		if changed := !mat.Equal(w, before[l]); changed != (rates[l] != 0) {
+			t.Errorf("layer %d changed = %v with learning rate %v", l, changed, rates[l])
+		}
+	}
+
+	broadcast := NewNeuralNetworkWithSeed([]int{2, 3, 3, 1}, 1)
+	scalar := NewNeuralNetworkWithSeed([]int{2, 3, 3, 1}, 1)
+	if _, err := broadcast.TrainLayerRates(inputs, targets, 10, []float64{0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scalar.Train(inputs, targets, 10, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	for l, w := range broadcast.Weights() {
+		if !mat.Equal(w, scalar.Weights()[l]) {
+			t.Errorf("a single rate trained layer %d differently from Train", l)
+		}
+	}
+	if _, err := nn.TrainLayerRates(inputs, targets, 1, []float64{0.1, 0.1}); err == nil {
+		t.Error("two rates for three layers gave no error")
+	}
+}
+
+func TestTrainSampleLearnsXOR(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 4, 1}, 1)
+	for epoch := 0; epoch < 5000; epoch++ {
+		for i := 0; i < 4; i++ {
+			if _, err := nn.TrainSample(inputs.RawRowView(i), targets.RawRowView(i), 0.5); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !xorSolved(nn) {
+		t.Errorf("online training did not solve XOR: %v", nn.Predict(inputs).RawMatrix().Data)
+	}
+	if _, err := nn.TrainSample([]float64{1}, []float64{0}, 0.5); err == nil {
+		t.Error("a one-feature sample gave no error")
+	}
+}
+
+func TestTrainSafeStopsOnNaN(t *testing.T) {
+	inputs, targets := xorData()
+	sgd := &SGD{Momentum: 0.9}
+	nn := New([]int{2, 4, 1}, WithSeed(1), WithActivation(Linear), WithOutputActivation(Linear), WithOptimizer(sgd))
+	history, err := nn.TrainSafe(inputs, targets, 100, 1000)
+	if err == nil {
+		t.Fatal("TrainSafe gave no error for diverging weights")
+	}
+	if !strings.Contains(err.Error(), "epoch") || !strings.Contains(err.Error(), "layer") {
+		t.Errorf("error %q does not name the epoch and layer", err)
+	}
+	if len(history) == 100 {
+		t.Error("TrainSafe ran every epoch")
+	}
+	if err := nn.checkFinite(); err != nil {
+		t.Errorf("restored network is corrupt: %v", err)
+	}
+	fresh, ok := nn.optimizer.(*SGD)
+	if !ok || fresh == sgd || fresh.Momentum != 0.9 || fresh.velocities != nil {
+		t.Errorf("optimizer %#v was not replaced by a fresh SGD with momentum 0.9", nn.optimizer)
+	}
+}
+
+func TestCheckFiniteBatchNorm(t *testing.T) {
+	nn := New([]int{2, 3, 1}, WithSeed(1), WithBatchNorm())
+	if err := nn.checkFinite(); err != nil {
+		t.Fatal(err)
+	}
+	for _, corrupt := range []func(bn *batchNorm){
+		func(bn *batchNorm) { bn.gamma.Set(0, 1, math.NaN()) },
+		func(bn *batchNorm) { bn.beta.Set(0, 1, math.Inf(1)) },
+		func(bn *batchNorm) { bn.runningMean.Set(0, 1, math.NaN()) },
+		func(bn *batchNorm) { bn.runningVar.Set(0, 1, math.Inf(1)) },
+	} {
+		nn := New([]int{2, 3, 1}, WithSeed(1), WithBatchNorm())
+		corrupt(nn.batchNorms[0])
+		if err := nn.checkFinite(); err == nil || !strings.Contains(err.Error(), "layer 1 batch normalization") {
+			t.Errorf("corrupt batch normalization gave error %v", err)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	inputs, targets := xorData()
+	a := NewNeuralNetworkWithInit([]int{2, 3, 1}, GlorotUniform, 1)
+	b := NewNeuralNetworkWithInit([]int{2, 3, 1}, GlorotUniform, 2)
+	if _, err := a.Train(inputs, targets, 50, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	a.Reset(9)
+	b.Reset(9)
+	fresh := NewNeuralNetworkWithInit([]int{2, 3, 1}, GlorotUniform, 9)
+	for l, w := range a.Weights() {
+		if !mat.Equal(w, b.Weights()[l]) {
+			t.Errorf("layer %d weights differ after resetting with the same seed", l)
+		}
+		if !mat.Equal(w, fresh.Weights()[l]) {
+			t.Errorf("layer %d weights differ from a new network with the same seed", l)
+		}
+		if mat.Max(a.biases[l]) != 0 || mat.Min(a.biases[l]) != 0 {
+			t.Errorf("layer %d biases were not zeroed", l)
+		}
+	}
+}
+
+// constPredictor is a mock Predictor giving every sample the same outputs
+type constPredictor []float64
+
+func (p constPredictor) Predict(inputs *mat.Dense) *mat.Dense {
+	r, _ := inputs.Dims()
+	outputs := mat.NewDense(r, len(p), nil)
+	for i := 0; i < r; i++ {
+		outputs.SetRow(i, p)
+	}
+	return outputs
+}
+
+func TestMockPredictor(t *testing.T) {
+	var p Predictor = constPredictor{1}
+	inputs, targets := xorData()
+	if got := Accuracy(p.Predict(inputs), targets, 0.5); got != 0.5 {
+		t.Errorf("always predicting 1 on XOR scores %v, want 0.5", got)
+	}
+	code, body := post(PredictHandler(p), "/predict", `{"inputs": [[0, 0, 0]]}`)
+	if code != http.StatusOK || body != "{\"outputs\":[[1]]}\n" {
+		t.Errorf("handler for the mock: status %d, body %q", code, body)
+	}
+}
+
+func TestAccumulationMatchesLargeBatch(t *testing.T) {
+	inputs, targets := randomDense(32, 3, 1), randomDense(32, 2, 2)
+	accumulated := New([]int{3, 4, 2}, WithSeed(1), WithAccumulationSteps(2))
+	single := New([]int{3, 4, 2}, WithSeed(1))
+	if _, err := accumulated.TrainMiniBatch(inputs, targets, 3, 16, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := single.TrainMiniBatch(inputs, targets, 3, 32, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	for l, w := range accumulated.Weights() {
+		if !mat.EqualApprox(w, single.Weights()[l], 1e-12) {
+			t.Errorf("layer %d: two accumulated batches of 16 differ from one of 32", l)
+		}
+	}
+}
+
+func TestTiedWeights(t *testing.T) {
+	inputs := randomDense(20, 4, 1)
+	nn := New([]int{4, 2, 4}, WithSeed(1), WithInit(GlorotUniform), WithTiedWeights(0, 1))
+	assertTied := func(when string, nn *NeuralNetwork) {
+		t.Helper()
+		w := nn.Weights()
+		if !mat.Equal(w[1], w[0].T()) {
+			t.Errorf("%s: decoder weights are not the encoder's transpose", when)
+		}
+	}
+	assertTied("after construction", nn)
+	history, err := nn.Train(inputs, inputs, 500, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTied("after training", nn)
+	if first, last := history[0], history[len(history)-1]; last >= first {
+		t.Errorf("reconstruction loss went from %v to %v, want it to drop", first, last)
+	}
+
+	var buf bytes.Buffer
+	if err := nn.SaveJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Train(inputs, inputs, 10, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	assertTied("after loading and training", loaded)
+	assertPanics(t, "WithTiedWeights on mismatched layers", func() { New([]int{4, 2, 3}, WithTiedWeights(0, 1)) })
+}
+
+func TestSampleWeightsScaleGradient(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	gradients := func(sampleWeights []float64) []*mat.Dense {
+		pass := nn.feedforward(inputs, trainMode)
+		weightGradients, _ := nn.backpropagate(pass, targets, sampleWeights, new(trainScratch))
+		return weightGradients
+	}
+	// Raising sample 2's weight from 1 to 2 adds its contribution again
+	uniform := gradients([]float64{1, 1, 1, 1})
+	doubled := gradients([]float64{1, 1, 2, 1})
+	alone := gradients([]float64{0, 0, 1, 0})
+	for l := range uniform {
+		added := new(mat.Dense)
+		added.Sub(doubled[l], uniform[l])
+		if !mat.EqualApprox(added, alone[l], 1e-15) {
+			t.Errorf("layer %d: doubling sample 2 added %v, its contribution is %v", l, added.RawMatrix().Data, alone[l].RawMatrix().Data)
+		}
+	}
+	if nilWeights := gradients(nil); !mat.Equal(nilWeights[0], uniform[0]) {
+		t.Error("nil sample weights differ from uniform weights")
+	}
+
+	if _, err := nn.TrainWeighted(inputs, targets, []float64{1, 1}, 1, 0.5); err == nil {
+		t.Error("TrainWeighted accepted 2 weights for 4 samples")
+	}
+	if _, err := nn.TrainWeighted(inputs, targets, []float64{1, -1, 1, 1}, 1, 0.5); err == nil {
+		t.Error("TrainWeighted accepted a negative weight")
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Accuracy returns the fraction of binary outputs classified correctly.
+// Every element is treated as a separate binary label: a prediction above
+// threshold counts as class 1, anything else as class 0. Targets are
+// labels, class 1 when above 0.5 whatever the threshold, so that raising
+// the threshold does not relabel the data.
+func Accuracy(predictions, targets *mat.Dense, threshold float64) float64 {
+	checkSameDims(predictions, targets)
+	r, c := predictions.Dims()
+	correct := 0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if (predictions.At(i, j) > threshold) == positiveLabel(targets.At(i, j)) {
+				correct++
+			}
+		}
+	}
+	return float64(correct) / float64(r*c)
+}
+
+// positiveLabel reports whether the binary target v is class 1
+func positiveLabel(v float64) bool {
+	return v > 0.5
+}
+
+// AccuracyArgmax returns the fraction of samples whose highest-scoring
+// output is the same class as the highest target, as for one-hot
+// multi-class targets
+func AccuracyArgmax(predictions, targets *mat.Dense) float64 {
+	checkSameDims(predictions, targets)
+	r, _ := predictions.Dims()
+	correct := 0
+	for i := 0; i < r; i++ {
+		if argmax(predictions.RawRowView(i)) == argmax(targets.RawRowView(i)) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(r)
+}
+
+// ConfusionMatrix counts how the samples' classes, taken as the argmax of
+// each row, were predicted: entry [i][j] is the number of samples of true
+// class i predicted as class j
+func ConfusionMatrix(predictions, targets *mat.Dense, numClasses int) [][]int {
+	checkSameDims(predictions, targets)
+	r, c := predictions.Dims()
+	if c > numClasses {
+		panic(fmt.Sprintf("nngo: outputs have %d classes but numClasses is %d", c, numClasses))
+	}
+	counts := make([][]int, numClasses)
+	for i := range counts {
+		counts[i] = make([]int, numClasses)
+	}
+	for i := 0; i < r; i++ {
+		counts[argmax(targets.RawRowView(i))][argmax(predictions.RawRowView(i))]++
+	}
+	return counts
+}
+
+// PrecisionRecallF1 scores thresholded binary outputs against binary
+// targets, treating every element as a separate label as Accuracy does:
+// threshold applies to the predictions, and targets above 0.5 are
+// positive.
+// Each score whose denominator is zero (no predicted positives for
+// precision, no actual positives for recall, both scores zero for F1) is 0.
+func PrecisionRecallF1(predictions, targets *mat.Dense, threshold float64) (precision, recall, f1 float64) {
+	checkSameDims(predictions, targets)
+	r, c := predictions.Dims()
+	var truePos, falsePos, falseNeg int
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			predicted := predictions.At(i, j) > threshold
+			actual := positiveLabel(targets.At(i, j))
+			switch {
+			case predicted && actual:
+				truePos++
+			case predicted:
+				falsePos++
+			case actual:
+				falseNeg++
+			}
+		}
+	}
+	if truePos+falsePos > 0 {
+		precision = float64(truePos) / float64(truePos+falsePos)
+	}
+	if truePos+falseNeg > 0 {
+		recall = float64(truePos) / float64(truePos+falseNeg)
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return precision, recall, f1
+}
+
+// PredictionEntropy returns the Shannon entropy, in nats, of each row of
+// probs taken as a probability distribution such as a softmax output. It
+// is 0 for a fully confident row and log(classes) for a uniform one, so
+// high values flag uncertain or unfamiliar inputs. Zero probabilities
+// contribute nothing.
+func PredictionEntropy(probs *mat.Dense) []float64 {
+	r, _ := probs.Dims()
+	entropies := make([]float64, r)
+	for i := range entropies {
+		for _, p := range probs.RawRowView(i) {
+			if p > 0 {
+				entropies[i] -= p * math.Log(p)
+			}
+		}
+	}
+	return entropies
+}
+
+// R2Score returns the coefficient of determination 1 - SS_res/SS_tot over
+// all output elements, where SS_tot is measured around the mean of every
+// target element. Constant targets have no variance to explain, so the
+// score is then 1 for a perfect fit and 0 otherwise.
+func R2Score(predictions, targets *mat.Dense) float64 {
+	checkSameDims(predictions, targets)
+	r, c := targets.Dims()
+	mean := mat.Sum(targets) / float64(r*c)
+	ssRes, ssTot := 0.0, 0.0
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			t := targets.At(i, j)
+			res := t - predictions.At(i, j)
+			ssRes += res * res
+			ssTot += (t - mean) * (t - mean)
+		}
+	}
+	if ssTot == 0 {
+		if ssRes == 0 {
+			return 1
+		}
+		return 0
+	}
+	return 1 - ssRes/ssTot
+}
+
+// ClassificationReport returns a text table of every class's precision,
+// recall, F1 score and support (number of true samples), followed by the
+// overall accuracy:
+//
+//	Class  Precision  Recall  F1    Support
+//	cat    1.00       0.50    0.67  2
+//	dog    0.67       1.00    0.80  2
+//	Accuracy: 0.75 (4 samples)
+//
+// Classes are the argmax of each row, or for single-column outputs whether
+// the value exceeds 0.5, giving classes 0 and 1. Class i is named
+// classNames[i], or by its index when classNames is too short. Scores with
+// a zero denominator are 0, as in PrecisionRecallF1.
+func ClassificationReport(predictions, targets *mat.Dense, classNames []string) string {
+	checkSameDims(predictions, targets)
+	r, c := predictions.Dims()
+	numClasses := max(c, 2)
+	counts := make([][]int, numClasses)
+	for i := range counts {
+		counts[i] = make([]int, numClasses)
+	}
+	correct := 0
+	for i := 0; i < r; i++ {
+		actual, predicted := reportClass(targets.RawRowView(i)), reportClass(predictions.RawRowView(i))
+		counts[actual][predicted]++
+		if actual == predicted {
+			correct++
+		}
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Class\tPrecision\tRecall\tF1\tSupport")
+	for k := 0; k < numClasses; k++ {
+		truePos, predictedPos, support := counts[k][k], 0, 0
+		for j := 0; j < numClasses; j++ {
+			predictedPos += counts[j][k]
+			support += counts[k][j]
+		}
+		var precision, recall, f1 float64
+		if predictedPos > 0 {
+			precision = float64(truePos) / float64(predictedPos)
+		}
+		if support > 0 {
+			recall = float64(truePos) / float64(support)
+		}
+		if precision+recall > 0 {
+			f1 = 2 * precision * recall / (precision + recall)
+		}
+		name := strconv.Itoa(k)
+		if k < len(classNames) {
+			name = classNames[k]
+		}
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t%d\n", name, precision, recall, f1, support)
+	}
+	w.Flush()
+	accuracy := 0.0
+	if r > 0 {
+		accuracy = float64(correct) / float64(r)
+	}
+	fmt.Fprintf(&b, "Accuracy: %.2f (%d samples)\n", accuracy, r)
+	return b.String()
+}
+
+// reportClass returns the class of a prediction or target row as
+// ClassificationReport counts it
+func reportClass(row []float64) int {
+	if len(row) == 1 {
+		if row[0] > 0.5 {
+			return 1
+		}
+		return 0
+	}
+	return argmax(row)
+}
+
+// argmax returns the index of the largest value in row, the first one on
+// ties
+func argmax(row []float64) int {
+	best := 0
+	for j, v := range row {
+		if v > row[best] {
+			best = j
+		}
+	}
+	return best
+}
+
+// checkSameDims panics unless predictions and targets have the same shape
+func checkSameDims(predictions, targets *mat.Dense) {
+	pr, pc := predictions.Dims()
+	tr, tc := targets.Dims()
+	if pr != tr || pc != tc {
+		panic(fmt.Sprintf("nngo: predictions are %dx%d but targets are %dx%d", pr, pc, tr, tc))
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestAccuracy(t *testing.T) {
+	targets := mat.NewDense(4, 1, []float64{0, 1, 1, 0})
+	perfect := mat.NewDense(4, 1, []float64{0.1, 0.9, 0.6, 0.4})
+	half := mat.NewDense(4, 1, []float64{0.1, 0.9, 0.4, 0.6})
+	if got := Accuracy(perfect, targets, 0.5); got != 1 {
+		t.Errorf("perfect binary accuracy %v, want 1", got)
+	}
+	if got := Accuracy(half, targets, 0.5); got != 0.5 {
+		t.Errorf("half-right binary accuracy %v, want 0.5", got)
+	}
+	// Targets stay labels at any threshold: with 1.5 every target would
+	// otherwise be class 0 and match every prediction
+	if got := Accuracy(perfect, targets, 1.5); got != 0.5 {
+		t.Errorf("accuracy at threshold 1.5 %v, want 0.5", got)
+	}
+	if got := Accuracy(perfect, targets, 0.7); got != 0.75 {
+		t.Errorf("accuracy at threshold 0.7 %v, want 0.75", got)
+	}
+
+	oneHot := OneHot([]int{0, 1, 2, 1}, 3)
+	perfect = mat.NewDense(4, 3, []float64{
+		0.8, 0.1, 0.1,
+		0.2, 0.7, 0.1,
+		0.1, 0.2, 0.7,
+		0.3, 0.4, 0.3,
+	})
+	half = mat.NewDense(4, 3, []float64{
+		0.8, 0.1, 0.1,
+		0.7, 0.2, 0.1,
+		0.1, 0.2, 0.7,
+		0.4, 0.3, 0.3,
+	})
+	if got := AccuracyArgmax(perfect, oneHot); got != 1 {
+		t.Errorf("perfect argmax accuracy %v, want 1", got)
+	}
+	if got := AccuracyArgmax(half, oneHot); got != 0.5 {
+		t.Errorf("half-right argmax accuracy %v, want 0.5", got)
+	}
+}
+
+func TestConfusionMatrix(t *testing.T) {
+	// True classes 0, 0, 1, 1, 2, 2 predicted as 0, 1, 1, 1, 0, 2
+	targets := OneHot([]int{0, 0, 1, 1, 2, 2}, 3)
+	predictions := OneHot([]int{0, 1, 1, 1, 0, 2}, 3)
+	want := [][]int{
+		{1, 1, 0},
+		{0, 2, 0},
+		{1, 0, 1},
+	}
+	got := ConfusionMatrix(predictions, targets, 3)
+	for i := range want {
+		for j := range want[i] {
+			if got[i][j] != want[i][j] {
+				t.Errorf("cell [%d][%d] = %d, want %d", i, j, got[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestR2Score(t *testing.T) {
+	targets := mat.NewDense(4, 1, []float64{1, 2, 3, 6})
+	if got := R2Score(targets, targets); got != 1 {
+		t.Errorf("perfect predictions score %v, want 1", got)
+	}
+	mean := mat.NewDense(4, 1, []float64{3, 3, 3, 3})
+	if got := R2Score(mean, targets); math.Abs(got) > 1e-15 {
+		t.Errorf("predicting the mean scores %v, want 0", got)
+	}
+	constant := mat.NewDense(2, 1, []float64{5, 5})
+	if got := R2Score(constant, constant); got != 1 {
+		t.Errorf("constant targets predicted exactly score %v, want 1", got)
+	}
+	if got := R2Score(mat.NewDense(2, 1, []float64{4, 6}), constant); got != 0 {
+		t.Errorf("constant targets predicted wrongly score %v, want 0", got)
+	}
+}
+
+func TestPrecisionRecallF1(t *testing.T) {
+	// 3 true positives, 1 false positive, 2 false negatives, 2 true negatives
+	predictions := mat.NewDense(8, 1, []float64{0.9, 0.8, 0.7, 0.6, 0.2, 0.1, 0.3, 0.4})
+	targets := mat.NewDense(8, 1, []float64{1, 1, 1, 0, 1, 1, 0, 0})
+	precision, recall, f1 := PrecisionRecallF1(predictions, targets, 0.5)
+	if want := 0.75; math.Abs(precision-want) > 1e-15 {
+		t.Errorf("precision %v, want %v", precision, want)
+	}
+	if want := 0.6; math.Abs(recall-want) > 1e-15 {
+		t.Errorf("recall %v, want %v", recall, want)
+	}
+	if want := 2 * 0.75 * 0.6 / 1.35; math.Abs(f1-want) > 1e-15 {
+		t.Errorf("F1 %v, want %v", f1, want)
+	}
+
+	none := mat.NewDense(8, 1, nil)
+	if precision, _, f1 := PrecisionRecallF1(none, targets, 0.5); precision != 0 || f1 != 0 {
+		t.Errorf("no predicted positives: precision %v and F1 %v, want 0", precision, f1)
+	}
+
+	// Scores thresholded at 1 are still compared with 0/1 labels
+	scores := mat.NewDense(2, 1, []float64{2, 0.5})
+	labels := mat.NewDense(2, 1, []float64{1, 0})
+	if precision, recall, _ := PrecisionRecallF1(scores, labels, 1); precision != 1 || recall != 1 {
+		t.Errorf("threshold 1: precision %v and recall %v, want 1", precision, recall)
+	}
+}
+
+func TestPredictionEntropy(t *testing.T) {
+	probs := mat.NewDense(2, 4, []float64{
+		0, 1, 0, 0,
+		0.25, 0.25, 0.25, 0.25,
+	})
+	entropies := PredictionEntropy(probs)
+	if entropies[0] != 0 {
+		t.Errorf("confident row entropy %v, want 0", entropies[0])
+	}
+	if want := math.Log(4); math.Abs(entropies[1]-want) > 1e-15 {
+		t.Errorf("uniform row entropy %v, want log(4) = %v", entropies[1], want)
+	}
+}
+
+func TestClassificationReport(t *testing.T) {
+	targets := mat.NewDense(4, 2, []float64{1, 0, 1, 0, 0, 1, 0, 1})
+	predictions := mat.NewDense(4, 2, []float64{0.9, 0.1, 0.3, 0.7, 0.2, 0.8, 0.4, 0.6})
+	want := "Class  Precision  Recall  F1    Support\n" +
+		"cat    1.00       0.50    0.67  2\n" +
+		"dog    0.67       1.00    0.80  2\n" +
+		"Accuracy: 0.75 (4 samples)\n"
+	if got := ClassificationReport(predictions, targets, []string{"cat", "dog"}); got != want {
+		t.Errorf("report\n%s\nwant\n%s", got, want)
+	}
+
+	report := ClassificationReport(predictions, targets, []string{"cat"})
+	if lines := strings.Split(report, "\n"); !strings.HasPrefix(lines[1], "cat ") || !strings.HasPrefix(lines[2], "1 ") {
+		t.Errorf("report with one class name\n%s\nwant the second class named 1", report)
+	}
+
+	binary := ClassificationReport(mat.NewDense(2, 1, []float64{0.2, 0.9}), mat.NewDense(2, 1, []float64{0, 1}), nil)
+	if !strings.Contains(binary, "Accuracy: 1.00 (2 samples)") {
+		t.Errorf("single-column report\n%s\nwant perfect accuracy over classes 0 and 1", binary)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Optimizer updates a parameter matrix in place from the gradient of the
+// loss with respect to it. Stateful optimizers keep their state per
+// parameter matrix, so one Optimizer should only serve one network.
+type Optimizer interface {
+	Update(weights, gradient *mat.Dense, learningRate float64)
+}
+
+// freshOptimizer returns a built-in optimizer with o's settings and no
+// accumulated state, or o itself for any other implementation
+func freshOptimizer(o Optimizer) Optimizer {
+	switch o := o.(type) {
+	case *SGD:
+		return &SGD{Momentum: o.Momentum, Nesterov: o.Nesterov}
+	case *Adam:
+		return &Adam{Beta1: o.Beta1, Beta2: o.Beta2, Epsilon: o.Epsilon}
+	case *Nadam:
+		return &Nadam{Beta1: o.Beta1, Beta2: o.Beta2, Epsilon: o.Epsilon}
+	case *RMSProp:
+		return &RMSProp{Rho: o.Rho, Epsilon: o.Epsilon}
+	case *Adagrad:
+		return &Adagrad{Epsilon: o.Epsilon}
+	}
+	return o
+}
+
+// SGD is gradient descent with optional momentum. Each update computes
+// v = Momentum*v + learningRate*gradient and then weights -= v, so with
+// Momentum 0 it is plain gradient descent. 0.9 is a sensible momentum when
+// gradients are noisy or progress along a consistent direction is slow.
+//
+// With Nesterov set the gradient is in effect taken at the look-ahead
+// position weights - Momentum*v rather than at the current weights. Using
+// the usual reformulation this is the step weights -= Momentum*v +
+// learningRate*gradient after v is updated, and it often converges faster
+// than classical momentum.
+type SGD struct {
+	Momentum float64
+	Nesterov bool
+
+	velocities map[*mat.Dense]*mat.Dense
+}
+
+// Update implements Optimizer
+func (o *SGD) Update(weights, gradient *mat.Dense, learningRate float64) {
+	step := new(mat.Dense)
+	step.Scale(learningRate, gradient)
+
+	if o.Momentum != 0 {
+		if o.velocities == nil {
+			o.velocities = make(map[*mat.Dense]*mat.Dense)
+		}
+		v, ok := o.velocities[weights]
+		if !ok {
+			r, c := weights.Dims()
+			v = mat.NewDense(r, c, nil)
+			o.velocities[weights] = v
+		}
+		v.Scale(o.Momentum, v)
+		v.Add(v, step)
+		if o.Nesterov {
+			lookAhead := new(mat.Dense)
+			lookAhead.Scale(o.Momentum, v)
+			step.Add(step, lookAhead)
+		} else {
+			step = v
+		}
+	}
+
+	weights.Sub(weights, step)
+}
+
+// Adam keeps exponentially decaying averages of past gradients (first
+// moment) and squared gradients (second moment) per weight and scales each
+// step by their bias-corrected ratio.
+type Adam struct {
+	Beta1   float64
+	Beta2   float64
+	Epsilon float64
+
+	states map[*mat.Dense]*adamState
+}
+
+type adamState struct {
+	m, v *mat.Dense
+	t    int
+}
+
+// NewAdam returns an Adam optimizer with the usual defaults
+// beta1 = 0.9, beta2 = 0.999 and epsilon = 1e-8
+func NewAdam() *Adam {
+	return &Adam{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8}
+}
+
+// Update implements Optimizer
+func (o *Adam) Update(weights, gradient *mat.Dense, learningRate float64) {
+	s := adamStateFor(&o.states, weights)
+	s.t++
+
+	correction1 := 1 - math.Pow(o.Beta1, float64(s.t))
+	correction2 := 1 - math.Pow(o.Beta2, float64(s.t))
+
+	r, c := weights.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			g := gradient.At(i, j)
+			m := o.Beta1*s.m.At(i, j) + (1-o.Beta1)*g
+			v := o.Beta2*s.v.At(i, j) + (1-o.Beta2)*g*g
+			s.m.Set(i, j, m)
+			s.v.Set(i, j, v)
+
+			mHat := m / correction1
+			vHat := v / correction2
+			weights.Set(i, j, weights.At(i, j)-learningRate*mHat/(math.Sqrt(vHat)+o.Epsilon))
+		}
+	}
+}
+
+// adamStateFor returns the moment estimates kept in *states for weights,
+// creating them on first use
+func adamStateFor(states *map[*mat.Dense]*adamState, weights *mat.Dense) *adamState {
+	if *states == nil {
+		*states = make(map[*mat.Dense]*adamState)
+	}
+	s, ok := (*states)[weights]
+	if !ok {
+		r, c := weights.Dims()
+		s = &adamState{m: mat.NewDense(r, c, nil), v: mat.NewDense(r, c, nil)}
+		(*states)[weights] = s
+	}
+	return s
+}
+
+// Nadam is Adam with Nesterov momentum: each step uses the first moment as
+// it will be after the next update, blending in the current gradient,
+// which often converges a little faster than Adam.
+type Nadam struct {
+	Beta1   float64
+	Beta2   float64
+	Epsilon float64
+
+	states map[*mat.Dense]*adamState
+}
+
+// NewNadam returns a Nadam optimizer with Adam's defaults
+// beta1 = 0.9, beta2 = 0.999 and epsilon = 1e-8
+func NewNadam() *Nadam {
+	return &Nadam{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8}
+}
+
+// Update implements Optimizer
+func (o *Nadam) Update(weights, gradient *mat.Dense, learningRate float64) {
+	s := adamStateFor(&o.states, weights)
+	s.t++
+
+	correction1 := 1 - math.Pow(o.Beta1, float64(s.t))
+	nextCorrection1 := 1 - math.Pow(o.Beta1, float64(s.t+1))
+	correction2 := 1 - math.Pow(o.Beta2, float64(s.t))
+
+	r, c := weights.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			g := gradient.At(i, j)
+			m := o.Beta1*s.m.At(i, j) + (1-o.Beta1)*g
+			v := o.Beta2*s.v.At(i, j) + (1-o.Beta2)*g*g
+			s.m.Set(i, j, m)
+			s.v.Set(i, j, v)
+
+			mHat := o.Beta1*m/nextCorrection1 + (1-o.Beta1)*g/correction1
+			vHat := v / correction2
+			weights.Set(i, j, weights.At(i, j)-learningRate*mHat/(math.Sqrt(vHat)+o.Epsilon))
+		}
+	}
+}
+
+// RMSProp keeps an exponentially decaying average of squared gradients per
+// weight, avg = Rho*avg + (1-Rho)*gradient², and divides each step by
+// sqrt(avg) + Epsilon, which suits non-stationary objectives.
+type RMSProp struct {
+	Rho     float64
+	Epsilon float64
+
+	averages map[*mat.Dense]*mat.Dense
+}
+
+// NewRMSProp returns an RMSProp optimizer with the usual defaults
+// rho = 0.9 and epsilon = 1e-8
+func NewRMSProp() *RMSProp {
+	return &RMSProp{Rho: 0.9, Epsilon: 1e-8}
+}
+
+// Update implements Optimizer
+func (o *RMSProp) Update(weights, gradient *mat.Dense, learningRate float64) {
+	if o.averages == nil {
+		o.averages = make(map[*mat.Dense]*mat.Dense)
+	}
+	avg, ok := o.averages[weights]
+	if !ok {
+		r, c := weights.Dims()
+		avg = mat.NewDense(r, c, nil)
+		o.averages[weights] = avg
+	}
+
+	r, c := weights.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			g := gradient.At(i, j)
+			a := o.Rho*avg.At(i, j) + (1-o.Rho)*g*g
+			avg.Set(i, j, a)
+			weights.Set(i, j, weights.At(i, j)-learningRate*g/(math.Sqrt(a)+o.Epsilon))
+		}
+	}
+}
+
+// Adagrad accumulates the sum of squared gradients per weight over all of
+// training and divides each step by sqrt(sum) + Epsilon, so rarely updated
+// weights take larger steps than frequently updated ones.
+type Adagrad struct {
+	Epsilon float64
+
+	sums map[*mat.Dense]*mat.Dense
+}
+
+// NewAdagrad returns an Adagrad optimizer with epsilon = 1e-8
+func NewAdagrad() *Adagrad {
+	return &Adagrad{Epsilon: 1e-8}
+}
+
+// Update implements Optimizer
+func (o *Adagrad) Update(weights, gradient *mat.Dense, learningRate float64) {
+	if o.sums == nil {
+		o.sums = make(map[*mat.Dense]*mat.Dense)
+	}
+	sum, ok := o.sums[weights]
+	if !ok {
+		r, c := weights.Dims()
+		sum = mat.NewDense(r, c, nil)
+		o.sums[weights] = sum
+	}
+
+	r, c := weights.Dims()
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			g := gradient.At(i, j)
+			s := sum.At(i, j) + g*g
+			sum.Set(i, j, s)
+			weights.Set(i, j, weights.At(i, j)-learningRate*g/(math.Sqrt(s)+o.Epsilon))
+		}
+	}
+}
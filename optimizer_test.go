@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// xorSolved reports whether every XOR prediction of nn rounds to its target
+func xorSolved(nn *NeuralNetwork) bool {
+	inputs, targets := xorData()
+	predictions := nn.Predict(inputs)
+	for i := 0; i < 4; i++ {
+		if math.Round(predictions.At(i, 0)) != targets.At(i, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAdamConvergesQuickly(t *testing.T) {
+	inputs, targets := xorData()
+	nn := New([]int{2, 4, 1}, WithSeed(1), WithOptimizer(NewAdam()))
+	if _, err := nn.Train(inputs, targets, 2000, 0.05); err != nil {
+		t.Fatal(err)
+	}
+	if !xorSolved(nn) {
+		t.Errorf("Adam did not solve XOR in 2000 epochs: %v", nn.Predict(inputs).RawMatrix().Data)
+	}
+}
+
+func TestMomentumSpeedsUpXOR(t *testing.T) {
+	inputs, targets := xorData()
+	finalLoss := func(momentum float64) float64 {
+		nn := New([]int{2, 4, 1}, WithSeed(1), WithOptimizer(&SGD{Momentum: momentum}))
+		history, err := nn.Train(inputs, targets, 1000, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return history[len(history)-1]
+	}
+	plain, momentum := finalLoss(0), finalLoss(0.9)
+	if momentum >= plain {
+		t.Errorf("loss after 1000 epochs: %v with momentum, %v without", momentum, plain)
+	}
+}
+
+func TestRMSPropBeatsSGD(t *testing.T) {
+	inputs, targets := xorData()
+	lossAfter500 := func(optimizer Optimizer) float64 {
+		nn := New([]int{2, 4, 1}, WithSeed(1), WithOptimizer(optimizer))
+		history, err := nn.Train(inputs, targets, 500, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return history[len(history)-1]
+	}
+	sgd, rmsprop := lossAfter500(&SGD{}), lossAfter500(NewRMSProp())
+	if rmsprop >= sgd {
+		t.Errorf("loss after 500 epochs: %v with RMSProp, %v with SGD", rmsprop, sgd)
+	}
+}
+
+func TestAdagradStepShrinks(t *testing.T) {
+	o := NewAdagrad()
+	weights := mat.NewDense(1, 1, nil)
+	gradient := mat.NewDense(1, 1, []float64{0.5})
+	previous := math.Inf(1)
+	for i := 0; i < 20; i++ {
+		before := weights.At(0, 0)
+		o.Update(weights, gradient, 0.1)
+		step := before - weights.At(0, 0)
+		if step <= 0 || step >= previous {
+			t.Fatalf("update %d stepped %v after %v, want a smaller positive step", i, step, previous)
+		}
+		previous = step
+	}
+}
+
+func TestNesterovBeatsMomentum(t *testing.T) {
+	inputs, targets := xorData()
+	epochsToSolve := func(nesterov bool) int {
+		nn := New([]int{2, 4, 1}, WithSeed(1), WithOptimizer(&SGD{Momentum: 0.9, Nesterov: nesterov}))
+		for epoch := 1; epoch <= 5000; epoch++ {
+			if _, err := nn.Train(inputs, targets, 1, 0.1); err != nil {
+				t.Fatal(err)
+			}
+			if xorSolved(nn) {
+				return epoch
+			}
+		}
+		return 5000
+	}
+	classical, nesterov := epochsToSolve(false), epochsToSolve(true)
+	if nesterov > classical {
+		t.Errorf("Nesterov solved XOR in %d epochs, classical momentum in %d", nesterov, classical)
+	}
+}
+
+func TestNadamMatchesAdam(t *testing.T) {
+	inputs, targets := xorData()
+	lossAfter500 := func(optimizer Optimizer) float64 {
+		nn := New([]int{2, 4, 1}, WithSeed(1), WithOptimizer(optimizer))
+		history, err := nn.Train(inputs, targets, 500, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return history[len(history)-1]
+	}
+	adam, nadam := lossAfter500(NewAdam()), lossAfter500(NewNadam())
+	if nadam > adam {
+		t.Errorf("loss after 500 epochs: %v with Nadam, %v with Adam", nadam, adam)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Option configures a network built by New
+type Option func(*networkConfig)
+
+// networkConfig collects the construction-time settings of a network
+type networkConfig struct {
+	hiddenActivation Activation
+	outputActivation Activation
+	init             InitStrategy
+	seed             int64
+	batchNorm        bool
+	// after holds settings applied to the network once it is built
+	after []func(*NeuralNetwork)
+}
+
+func defaultConfig() networkConfig {
+	return networkConfig{
+		hiddenActivation: Sigmoid,
+		outputActivation: Sigmoid,
+		init:             UniformInit,
+		seed:             time.Now().UnixNano(),
+	}
+}
+
+// New creates a new neural network with the given layer sizes, input layer
+// first. Without options it matches NewNeuralNetwork: sigmoid activations,
+// uniform [0, 1) weights from a time-based seed, plain SGD and MSE loss.
+func New(layerSizes []int, opts ...Option) *NeuralNetwork {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	nn := newNeuralNetwork(layerSizes, config)
+	for _, set := range config.after {
+		set(nn)
+	}
+	return nn
+}
+
+// networkOption returns an Option for a setting that does not affect how
+// the network is built and is applied to it afterwards
+func networkOption(set func(*NeuralNetwork)) Option {
+	return func(c *networkConfig) { c.after = append(c.after, set) }
+}
+
+// WithSeed seeds weight initialization and training randomness, so equal
+// seeds give identical networks
+func WithSeed(seed int64) Option {
+	return func(c *networkConfig) { c.seed = seed }
+}
+
+// WithActivation sets the activation of every hidden layer
+func WithActivation(activation Activation) Option {
+	return func(c *networkConfig) { c.hiddenActivation = activation }
+}
+
+// WithOutputActivation sets the activation of the output layer
+func WithOutputActivation(activation Activation) Option {
+	return func(c *networkConfig) { c.outputActivation = activation }
+}
+
+// WithInit selects how the initial weights are drawn
+func WithInit(init InitStrategy) Option {
+	return func(c *networkConfig) { c.init = init }
+}
+
+// WithBatchNorm adds batch normalization to every hidden layer: each
+// unit's pre-activation is normalized across the batch and then scaled and
+// shifted by learned parameters. Predict uses running averages of the
+// training batches' statistics. Gradient clipping and weight decay do not
+// apply to the scale and shift.
+func WithBatchNorm() Option {
+	return func(c *networkConfig) { c.batchNorm = true }
+}
+
+// WithTargetNormalization makes training standardize every target column
+// to zero mean and unit variance, using the column statistics of the
+// targets passed to the first training call, and makes Predict map outputs
+// back to the original units. This helps when target columns have very
+// different scales; pair it with a Linear output layer. Reported training
+// losses are measured in the standardized units. Class labels of
+// SparseCategoricalCrossEntropy cannot be normalized, and training such a
+// network returns an error.
+func WithTargetNormalization() Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.normalizeTargets = true })
+}
+
+// WithCheckpoints makes training save the network to dir with SaveJSON
+// after every `every` epochs, as checkpoint-00000025.json after epoch 25
+// and so on, so an interrupted run can resume from the latest checkpoint
+// with LoadJSON, which restores a built-in loss but not a custom Loss or
+// the optimizer state. Checkpoints are written atomically. A failed write
+// stops training with an error. every <= 0 disables checkpoints.
+func WithCheckpoints(every int, dir string) Option {
+	return networkOption(func(nn *NeuralNetwork) {
+		nn.checkpointEvery, nn.checkpointDir = every, dir
+	})
+}
+
+// WithLabelSmoothing trains classifiers on targets softened by
+// SmoothLabels with the given epsilon, discouraging overconfident
+// predictions. Reported training losses are measured against the smoothed
+// targets, except with SparseCategoricalCrossEntropy: its integer labels
+// are kept, the smoothing is applied to the gradient of a SoftmaxOutput
+// layer, and losses are measured against the labels. It panics unless
+// 0 <= epsilon < 1.
+func WithLabelSmoothing(epsilon float64) Option {
+	if epsilon < 0 || epsilon >= 1 {
+		panic(fmt.Sprintf("nngo: label smoothing %v outside [0, 1)", epsilon))
+	}
+	return networkOption(func(nn *NeuralNetwork) { nn.labelSmoothing = epsilon })
+}
+
+// WithInputValidation makes every training call check its inputs and
+// targets with ValidateInputs first and return the error instead of
+// training on NaN or infinite values
+func WithInputValidation() Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.validateInputs = true })
+}
+
+// WithAccumulationSteps makes TrainMiniBatch average the gradients of
+// steps consecutive batches into a single update, simulating batches steps
+// times larger with the memory of one. An epoch's leftover batches form a
+// final, smaller group. Batch normalization still uses each batch's own
+// statistics. Values <= 1 update after every batch.
+func WithAccumulationSteps(steps int) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.accumulationSteps = steps })
+}
+
+// WithTiedWeights ties the weights of layer decoder to the transpose of
+// those of layer encoder, counting weight matrices from 0 as Weights does,
+// as in autoencoders whose decoder mirrors the encoder. The pair shares a
+// single set of weights: training transposes the decoder's gradient onto
+// the encoder's and updates both together, while each layer keeps its own
+// biases. Freezing the encoder freezes the shared weights. It panics
+// unless the decoder's weights have the encoder's shape transposed and
+// neither layer is already tied to a third.
+func WithTiedWeights(encoder, decoder int) Option {
+	return networkOption(func(nn *NeuralNetwork) {
+		if err := nn.tieWeights(encoder, decoder); err != nil {
+			panic(err.Error())
+		}
+	})
+}
+
+// WithTask sets whether Evaluate scores the network as a Regression or a
+// Classification model. Networks default to Regression.
+func WithTask(task Task) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.task = task })
+}
+
+// WithLearningRate sets the learning rate Fit trains with
+func WithLearningRate(learningRate float64) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.learningRate = learningRate })
+}
+
+// Deterministic forces single-goroutine execution: the network computes
+// every step on the calling goroutine instead of splitting large
+// element-wise operations across goroutines, which helps when the caller
+// already runs many networks in parallel or wants simpler profiles.
+// Reproducibility does not depend on it. The parallel steps compute every
+// element independently and give identical results, and all randomness,
+// from weight initialization to dropout masks and shuffling, comes from
+// the network's single generator, so with WithSeed the same seed and data
+// always give identical weights either way.
+func Deterministic() Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.deterministic = true })
+}
+
+// WithOutputTransform makes Predict return transform of the network's
+// outputs, for example an inverse transform such as exp for a model trained
+// on log targets. Training, TrainWithValidation's validation loss,
+// Evaluate and PermutationImportance work on the untransformed outputs. A
+// nil transform leaves them unchanged.
+func WithOutputTransform(transform func(*mat.Dense) *mat.Dense) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.outputTransform = transform })
+}
+
+// WithOptimizer sets the optimizer used by training, see SetOptimizer
+func WithOptimizer(optimizer Optimizer) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.optimizer = optimizer })
+}
+
+// WithLoss sets the loss minimized by training, see SetLoss
+func WithLoss(loss Loss) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.loss = loss })
+}
+
+// WithProgress registers a callback invoked after every training epoch
+// with the epoch number, counting from 0, and that epoch's loss as recorded
+// in the returned history. A nil callback does nothing.
+func WithProgress(progress func(epoch int, loss float64)) Option {
+	return networkOption(func(nn *NeuralNetwork) { nn.progress = progress })
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNewOptions(t *testing.T) {
+	adam := NewAdam()
+	nn := New([]int{2, 3, 1},
+		WithSeed(7),
+		WithActivation(Tanh),
+		WithOutputActivation(Linear),
+		WithInit(HeNormal),
+		WithOptimizer(adam),
+		WithLoss(MAE{}),
+	)
+	if got := nn.activations[0].Name; got != "tanh" {
+		t.Errorf("hidden activation %q, want tanh", got)
+	}
+	if got := nn.activations[1].Name; got != "linear" {
+		t.Errorf("output activation %q, want linear", got)
+	}
+	if nn.init != HeNormal {
+		t.Errorf("init strategy %v, want %v", nn.init, HeNormal)
+	}
+	if nn.optimizer != adam {
+		t.Errorf("optimizer %T, want the given *Adam", nn.optimizer)
+	}
+	if _, ok := nn.loss.(MAE); !ok {
+		t.Errorf("loss %T, want MAE", nn.loss)
+	}
+	same := New([]int{2, 3, 1}, WithSeed(7), WithInit(HeNormal), WithActivation(Tanh), WithOutputActivation(Linear))
+	if !mat.Equal(nn.Weights()[0], same.Weights()[0]) {
+		t.Error("WithSeed(7) did not reproduce the initial weights")
+	}
+}
+
+func TestProgressCallback(t *testing.T) {
+	inputs, targets := xorData()
+	var epochs []int
+	var losses []float64
+	nn := New([]int{2, 2, 1}, WithSeed(1), WithProgress(func(epoch int, loss float64) {
+		epochs = append(epochs, epoch)
+		losses = append(losses, loss)
+	}))
+	history, err := nn.Train(inputs, targets, 25, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(epochs) != 25 {
+		t.Fatalf("callback ran %d times, want 25", len(epochs))
+	}
+	for i, epoch := range epochs {
+		if epoch != i {
+			t.Errorf("call %d got epoch %d", i, epoch)
+		}
+		if losses[i] != history[i] {
+			t.Errorf("epoch %d: callback loss %v, history %v", i, losses[i], history[i])
+		}
+	}
+
+	silent := New([]int{2, 2, 1}, WithSeed(1), WithProgress(nil))
+	if _, err := silent.Train(inputs, targets, 3, 0.5); err != nil {
+		t.Errorf("nil callback: %v", err)
+	}
+}
+
+func TestTargetNormalization(t *testing.T) {
+	inputs := randomDense(50, 1, 1)
+	targets := mat.NewDense(50, 2, nil)
+	for i := 0; i < 50; i++ {
+		x := inputs.At(i, 0)
+		targets.Set(i, 0, 0.01*x)
+		targets.Set(i, 1, 1000*x+5000)
+	}
+	nn := New([]int{1, 2}, WithSeed(1), WithOutputActivation(Linear), WithTargetNormalization())
+	if _, err := nn.Train(inputs, targets, 2000, 0.1); err != nil {
+		t.Fatal(err)
+	}
+	predictions := nn.Predict(inputs)
+	for j, tol := range []float64{1e-4, 10} {
+		for i := 0; i < 50; i++ {
+			if d := math.Abs(predictions.At(i, j) - targets.At(i, j)); d > tol {
+				t.Fatalf("column %d row %d predicted %v, target %v", j, i, predictions.At(i, j), targets.At(i, j))
+			}
+		}
+	}
+}
+
+func TestDeterministicRuns(t *testing.T) {
+	// 200 samples of 64 hidden units exceed parallelThreshold, and
+	// dropout and shuffling draw from the random generator
+	inputs, targets := randomDense(200, 4, 1), randomDense(200, 1, 2)
+	run := func() []*mat.Dense {
+		nn := New([]int{4, 64, 1}, WithSeed(5), Deterministic())
+		nn.SetDropout(0.2)
+		nn.SetShuffle(true)
+		if _, err := nn.TrainMiniBatch(inputs, targets, 5, 100, 0.1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nn.Train(inputs, targets, 5, 0.1); err != nil {
+			t.Fatal(err)
+		}
+		return nn.Weights()
+	}
+	first, second := run(), run()
+	for l := range first {
+		if !mat.Equal(first[l], second[l]) {
+			t.Errorf("layer %d weights differ between deterministic runs", l)
+		}
+	}
+}
+
+func TestOutputTransform(t *testing.T) {
+	X, y := xorData()
+	exp := func(m *mat.Dense) *mat.Dense {
+		out := new(mat.Dense)
+		out.Apply(func(_, _ int, v float64) float64 { return math.Exp(v) }, m)
+		return out
+	}
+	plain := New([]int{2, 3, 1}, WithSeed(1), WithOutputActivation(Linear))
+	transformed := New([]int{2, 3, 1}, WithSeed(1), WithOutputActivation(Linear), WithOutputTransform(exp))
+	if !mat.Equal(transformed.Predict(X), exp(plain.Predict(X))) {
+		t.Error("Predict with an exp output transform is not exp of the plain outputs")
+	}
+	plainLoss, _ := plain.Evaluate(X, y)
+	if loss, _ := transformed.Evaluate(X, y); loss != plainLoss {
+		t.Errorf("Evaluate loss %v with the transform, want the untransformed %v", loss, plainLoss)
+	}
+	plainImportance := plain.PermutationImportance(X, y, MSE{}.Loss)
+	for j, got := range transformed.PermutationImportance(X, y, MSE{}.Loss) {
+		if got != plainImportance[j] {
+			t.Errorf("feature %d importance %v with the transform, want the untransformed %v", j, got, plainImportance[j])
+		}
+	}
+
+	identity := New([]int{2, 3, 1}, WithSeed(1), WithOutputActivation(Linear), WithOutputTransform(nil))
+	if !mat.Equal(identity.Predict(X), plain.Predict(X)) {
+		t.Error("a nil output transform changed Predict")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// parallelThreshold is the number of matrix elements above which
+// element-wise functions are split across goroutines. Below it the
+// goroutine overhead outweighs the work.
+const parallelThreshold = 10000
+
+// applyElementwise sets every element of dst to f of the matching element
+// of m, which must have dst's shape. Large matrices are processed in
+// parallel unless serial is set.
+func applyElementwise(dst, m *mat.Dense, f func(float64) float64, serial bool) {
+	if r, c := m.Dims(); serial || r*c <= parallelThreshold || runtime.NumCPU() == 1 {
+		dst.Apply(func(_, _ int, v float64) float64 { return f(v) }, m)
+		return
+	}
+	applyParallel(dst, m, f)
+}
+
+// applyParallel is applyElementwise with the rows of m split into
+// runtime.NumCPU() contiguous ranges, one goroutine each
+func applyParallel(dst, m *mat.Dense, f func(float64) float64) {
+	r, _ := m.Dims()
+	workers := max(1, min(runtime.NumCPU(), r))
+	chunk := (r + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < r; start += chunk {
+		end := min(start+chunk, r)
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				out := dst.RawRowView(i)
+				for j, v := range m.RawRowView(i) {
+					out[j] = f(v)
+				}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestParallelMatchesSerial(t *testing.T) {
+	// 300x70 is above parallelThreshold and does not split evenly
+	m := randomDense(300, 70, 1)
+	serial := mat.NewDense(300, 70, nil)
+	parallel := mat.NewDense(300, 70, nil)
+	applyElementwise(serial, m, sigmoid, true)
+	applyParallel(parallel, m, sigmoid)
+	if !mat.Equal(parallel, serial) {
+		t.Error("parallel activation differs from the serial one")
+	}
+}
+
+// benchmarkApply applies sigmoid to a 1000x1000 matrix, serially or not
+func benchmarkApply(b *testing.B, serial bool) {
+	m := randomDense(1000, 1000, 1)
+	dst := mat.NewDense(1000, 1000, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		applyElementwise(dst, m, sigmoid, serial)
+	}
+}
+
+func BenchmarkApplySerial(b *testing.B) {
+	benchmarkApply(b, true)
+}
+
+func BenchmarkApplyParallel(b *testing.B) {
+	benchmarkApply(b, false)
+}
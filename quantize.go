@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// QuantizeInt8 stores every weight as a signed byte for compact storage,
+// about an eighth of the float64 size. Each layer l has a scale
+// scales[l] = max|w| / 127, and w is stored as round(w / scales[l]) in
+// layer order, each matrix row-major. Dequantizing with SetQuantizedInt8
+// restores every weight to within half a scale step, so coarse layers with
+// a few large weights lose the most precision and predictions shift
+// slightly; check them on held-out data before deploying. Biases are not
+// quantized.
+func (nn *NeuralNetwork) QuantizeInt8() ([]byte, []float64) {
+	var data []byte
+	scales := make([]float64, len(nn.weights))
+	for l, w := range nn.weights {
+		values := mat.DenseCopyOf(w).RawMatrix().Data
+		maxAbs := 0.0
+		for _, v := range values {
+			maxAbs = math.Max(maxAbs, math.Abs(v))
+		}
+		scales[l] = maxAbs / math.MaxInt8
+		for _, v := range values {
+			q := 0.0
+			if scales[l] != 0 {
+				q = math.Round(v / scales[l])
+			}
+			data = append(data, byte(int8(q)))
+		}
+	}
+	return data, scales
+}
+
+// SetQuantizedInt8 replaces the network's weights with the dequantized
+// ones from data and scales as produced by QuantizeInt8 for a network of
+// the same layer sizes. Nothing is changed if the sizes do not match.
+func (nn *NeuralNetwork) SetQuantizedInt8(data []byte, scales []float64) error {
+	if len(scales) != len(nn.weights) {
+		return fmt.Errorf("nngo: got %d quantization scales, network has %d layers", len(scales), len(nn.weights))
+	}
+	total := 0
+	for _, w := range nn.weights {
+		r, c := w.Dims()
+		total += r * c
+	}
+	if len(data) != total {
+		return fmt.Errorf("nngo: got %d quantized weights, network has %d", len(data), total)
+	}
+
+	weights := make([]*mat.Dense, len(nn.weights))
+	for l, w := range nn.weights {
+		r, c := w.Dims()
+		values := make([]float64, r*c)
+		for i := range values {
+			values[i] = float64(int8(data[i])) * scales[l]
+		}
+		data = data[r*c:]
+		weights[l] = mat.NewDense(r, c, values)
+	}
+	return nn.SetWeights(weights)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantizeInt8(t *testing.T) {
+	inputs, targets := xorData()
+	nn := New([]int{2, 8, 1}, WithSeed(1), WithInit(GlorotUniform))
+	if _, err := nn.Train(inputs, targets, 2000, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	data, scales := nn.QuantizeInt8()
+	if want := 2*8 + 8*1; len(data) != want {
+		t.Fatalf("got %d quantized weights, want %d", len(data), want)
+	}
+
+	restored := nn.Clone()
+	if err := restored.SetQuantizedInt8(data, scales); err != nil {
+		t.Fatal(err)
+	}
+	for l, w := range restored.Weights() {
+		original := nn.Weights()[l]
+		r, c := w.Dims()
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				if d := math.Abs(w.At(i, j) - original.At(i, j)); d > scales[l]/2+1e-12 {
+					t.Errorf("layer %d weight (%d, %d) moved by %v, more than half the step %v", l, i, j, d, scales[l])
+				}
+			}
+		}
+	}
+	want, got := nn.Predict(inputs), restored.Predict(inputs)
+	for i := 0; i < 4; i++ {
+		if d := math.Abs(got.At(i, 0) - want.At(i, 0)); d > 0.05 {
+			t.Errorf("sample %d prediction moved from %v to %v", i, want.At(i, 0), got.At(i, 0))
+		}
+	}
+
+	if err := restored.SetQuantizedInt8(data[:3], scales); err == nil {
+		t.Error("SetQuantizedInt8 accepted too few weights")
+	}
+}
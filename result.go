@@ -0,0 +1,36 @@
+package main
+
+import "time"
+
+// TrainResult summarizes a training run
+type TrainResult struct {
+	// History holds the loss recorded for every epoch that ran, as
+	// returned by the training method
+	History []float64
+	// FinalLoss is the last entry of History, or 0 when no epoch ran
+	FinalLoss float64
+	// Epochs is the number of epochs that ran, fewer than requested when
+	// training stopped early or failed
+	Epochs int
+	// Duration is the wall-clock time training took
+	Duration time.Duration
+}
+
+// RunTraining calls train, typically a closure around one of the training
+// methods, and summarizes the run:
+//
+//	result, err := RunTraining(func() ([]float64, error) {
+//		return nn.TrainWithValidation(trainIn, trainTgt, valIn, valTgt, 500, 0.1, 10)
+//	})
+//
+// The result describes the epochs that ran even when train returns an
+// error, which is passed through.
+func RunTraining(train func() ([]float64, error)) (TrainResult, error) {
+	start := time.Now()
+	history, err := train()
+	result := TrainResult{History: history, Epochs: len(history), Duration: time.Since(start)}
+	if len(history) > 0 {
+		result.FinalLoss = history[len(history)-1]
+	}
+	return result, err
+}
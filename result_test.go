@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunTraining(t *testing.T) {
+	inputs, targets := xorData()
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	result, err := RunTraining(func() ([]float64, error) {
+		return nn.Train(inputs, targets, 200, 0.5)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Epochs != 200 || len(result.History) != 200 {
+		t.Errorf("%d epochs with %d losses, want 200", result.Epochs, len(result.History))
+	}
+	if result.FinalLoss != result.History[199] || result.FinalLoss <= 0 {
+		t.Errorf("final loss %v, last history entry %v", result.FinalLoss, result.History[199])
+	}
+	if result.Duration <= 0 {
+		t.Errorf("duration %v, want positive", result.Duration)
+	}
+
+	failure := errors.New("stopped")
+	result, err = RunTraining(func() ([]float64, error) { return []float64{0.3, 0.2}, failure })
+	if err != failure || result.Epochs != 2 || result.FinalLoss != 0.2 {
+		t.Errorf("failed run gave %+v and %v", result, err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math/rand"
+	randv2 "math/rand/v2"
+)
+
+// pcgSource adapts a PCG generator, whose state can be copied, to the
+// rand.Source64 that rand.Rand draws from
+type pcgSource struct {
+	pcg *randv2.PCG
+}
+
+// newRand returns a generator seeded by seed together with its source
+func newRand(seed int64) (*rand.Rand, *pcgSource) {
+	src := &pcgSource{pcg: randv2.NewPCG(uint64(seed), 0)}
+	return rand.New(src), src
+}
+
+// Int63 implements rand.Source
+func (s *pcgSource) Int63() int64 {
+	return int64(s.pcg.Uint64() >> 1)
+}
+
+// Uint64 implements rand.Source64
+func (s *pcgSource) Uint64() uint64 {
+	return s.pcg.Uint64()
+}
+
+// Seed implements rand.Source
+func (s *pcgSource) Seed(seed int64) {
+	s.pcg.Seed(uint64(seed), 0)
+}
+
+// clone returns a generator that continues from s's current state without
+// advancing s, together with its source
+func (s *pcgSource) clone() (*rand.Rand, *pcgSource) {
+	state := *s.pcg
+	src := &pcgSource{pcg: &state}
+	return rand.New(src), src
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// LearningRateSchedule returns the learning rate to use for an epoch,
+// counting from 0
+type LearningRateSchedule func(epoch int) float64
+
+// ConstantLR always returns learningRate
+func ConstantLR(learningRate float64) LearningRateSchedule {
+	return func(int) float64 { return learningRate }
+}
+
+// StepDecay starts at initial and multiplies the rate by gamma every
+// stepSize epochs: initial * gamma^(epoch / stepSize). It panics unless
+// stepSize is positive.
+func StepDecay(initial, gamma float64, stepSize int) LearningRateSchedule {
+	if stepSize <= 0 {
+		panic(fmt.Sprintf("nngo: step decay step size %d, must be positive", stepSize))
+	}
+	return func(epoch int) float64 {
+		return initial * math.Pow(gamma, float64(epoch/stepSize))
+	}
+}
+
+// ExponentialDecay starts at initial and multiplies the rate by decay every
+// epoch: initial * decay^epoch
+func ExponentialDecay(initial, decay float64) LearningRateSchedule {
+	return func(epoch int) float64 {
+		return initial * math.Pow(decay, float64(epoch))
+	}
+}
+
+// CosineAnnealing decays the rate from lrMax at epoch 0 to lrMin at epoch
+// totalEpochs along half a cosine:
+// lrMin + (lrMax-lrMin)/2 * (1 + cos(pi * epoch / totalEpochs)).
+// Later epochs stay at lrMin. It panics unless totalEpochs is positive.
+func CosineAnnealing(lrMax, lrMin float64, totalEpochs int) LearningRateSchedule {
+	if totalEpochs <= 0 {
+		panic(fmt.Sprintf("nngo: cosine annealing over %d epochs, must be positive", totalEpochs))
+	}
+	return func(epoch int) float64 {
+		if epoch >= totalEpochs {
+			return lrMin
+		}
+		return cosineRate(lrMax, lrMin, float64(epoch)/float64(totalEpochs))
+	}
+}
+
+// CosineWarmRestarts repeats a CosineAnnealing cycle of period epochs, so
+// the rate jumps back to lrMax at every multiple of period. It panics
+// unless period is positive.
+func CosineWarmRestarts(lrMax, lrMin float64, period int) LearningRateSchedule {
+	if period <= 0 {
+		panic(fmt.Sprintf("nngo: cosine restart period %d, must be positive", period))
+	}
+	return func(epoch int) float64 {
+		return cosineRate(lrMax, lrMin, float64(epoch%period)/float64(period))
+	}
+}
+
+// cosineRate is the annealed rate after the given fraction of a cycle
+func cosineRate(lrMax, lrMin, fraction float64) float64 {
+	return lrMin + 0.5*(lrMax-lrMin)*(1+math.Cos(math.Pi*fraction))
+}
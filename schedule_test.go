@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// assertPanics fails t unless f panics
+func assertPanics(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", name)
+		}
+	}()
+	f()
+}
+
+func TestStepDecay(t *testing.T) {
+	schedule := StepDecay(0.1, 0.5, 10)
+	for _, tc := range []struct {
+		epoch int
+		want  float64
+	}{
+		{0, 0.1},
+		{9, 0.1},
+		{10, 0.05},
+		{20, 0.025},
+	} {
+		if got := schedule(tc.epoch); math.Abs(got-tc.want) > 1e-15 {
+			t.Errorf("epoch %d: rate %v, want %v", tc.epoch, got, tc.want)
+		}
+	}
+	if got := ExponentialDecay(1, 0.9)(2); math.Abs(got-0.81) > 1e-15 {
+		t.Errorf("exponential decay at epoch 2: %v, want 0.81", got)
+	}
+	assertPanics(t, "StepDecay(0.1, 0.5, 0)", func() { StepDecay(0.1, 0.5, 0) })
+}
+
+func TestCosineAnnealing(t *testing.T) {
+	schedule := CosineAnnealing(0.1, 0.001, 50)
+	if got := schedule(0); got != 0.1 {
+		t.Errorf("epoch 0: rate %v, want lrMax 0.1", got)
+	}
+	if got := schedule(25); math.Abs(got-0.0505) > 1e-15 {
+		t.Errorf("epoch 25: rate %v, want the midpoint 0.0505", got)
+	}
+	if got := schedule(50); math.Abs(got-0.001) > 1e-15 {
+		t.Errorf("epoch 50: rate %v, want lrMin 0.001", got)
+	}
+
+	restarts := CosineWarmRestarts(0.1, 0.001, 10)
+	if restarts(0) != 0.1 || restarts(10) != 0.1 || restarts(5) != schedule(25) {
+		t.Errorf("warm restarts gave %v, %v and %v at epochs 0, 10 and 5", restarts(0), restarts(10), restarts(5))
+	}
+	assertPanics(t, "CosineAnnealing over 0 epochs", func() { CosineAnnealing(0.1, 0.001, 0) })
+	assertPanics(t, "CosineWarmRestarts with period 0", func() { CosineWarmRestarts(0.1, 0.001, 0) })
+}
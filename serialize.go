@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// networkData is the serialized layout of a NeuralNetwork shared by the
+// JSON and gob encodings. Weights and biases are stored row-major, one flat
+// slice per layer.
+type networkData struct {
+	LayerSizes  []int       `json:"layerSizes"`
+	Activations []string    `json:"activations"`
+	Weights     [][]float64 `json:"weights"`
+	Biases      [][]float64 `json:"biases"`
+	// Loss names the built-in loss; it is empty for a custom Loss, and
+	// such networks load with MSE
+	Loss string `json:"loss,omitempty"`
+	// BatchNorms holds one entry per layer when any layer has batch
+	// normalization; layers without it have an empty entry
+	BatchNorms []batchNormData `json:"batchNorms,omitempty"`
+	// TargetMean and TargetStd hold the fitted target normalization, if any
+	TargetMean []float64 `json:"targetMean,omitempty"`
+	TargetStd  []float64 `json:"targetStd,omitempty"`
+	// TiedWeights lists the encoder and decoder layer of every weight tie
+	TiedWeights [][2]int `json:"tiedWeights,omitempty"`
+}
+
+// batchNormData is the serialized form of a layer's batch normalization
+type batchNormData struct {
+	Gamma       []float64 `json:"gamma,omitempty"`
+	Beta        []float64 `json:"beta,omitempty"`
+	RunningMean []float64 `json:"runningMean,omitempty"`
+	RunningVar  []float64 `json:"runningVar,omitempty"`
+}
+
+// data captures the network's layer sizes, activations, weights and biases
+func (nn *NeuralNetwork) data() networkData {
+	doc := networkData{LayerSizes: nn.layerSizes, TargetMean: nn.targetMean, TargetStd: nn.targetStd}
+	doc.Loss, _ = lossName(nn.loss)
+	for l := range nn.weights {
+		doc.Activations = append(doc.Activations, nn.activations[l].Name)
+		doc.Weights = append(doc.Weights, mat.DenseCopyOf(nn.weights[l]).RawMatrix().Data)
+		doc.Biases = append(doc.Biases, mat.DenseCopyOf(nn.biases[l]).RawMatrix().Data)
+	}
+	for _, tie := range nn.ties {
+		doc.TiedWeights = append(doc.TiedWeights, [2]int{tie.encoder, tie.decoder})
+	}
+	for l, bn := range nn.batchNorms {
+		if bn == nil {
+			continue
+		}
+		if doc.BatchNorms == nil {
+			doc.BatchNorms = make([]batchNormData, len(nn.weights))
+		}
+		doc.BatchNorms[l] = batchNormData{
+			Gamma:       mat.DenseCopyOf(bn.gamma).RawMatrix().Data,
+			Beta:        mat.DenseCopyOf(bn.beta).RawMatrix().Data,
+			RunningMean: mat.DenseCopyOf(bn.runningMean).RawMatrix().Data,
+			RunningVar:  mat.DenseCopyOf(bn.runningVar).RawMatrix().Data,
+		}
+	}
+	return doc
+}
+
+// SaveJSON writes the network's layer sizes, activations, weights and
+// biases, plus any batch normalization parameters, target normalization
+// and weight ties, to w as JSON. A built-in loss is saved by name; a
+// custom Loss and the optimizer state are not saved.
+func (nn *NeuralNetwork) SaveJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(nn.data())
+}
+
+// LoadJSON reads a network written by SaveJSON. The loaded network
+// produces exactly the same predictions as the saved one and starts
+// training with the saved built-in loss, MSE if none was saved, and a
+// fresh SGD optimizer.
+func LoadJSON(r io.Reader) (*NeuralNetwork, error) {
+	var doc networkData
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("nngo: decoding network: %w", err)
+	}
+	return doc.network()
+}
+
+// GobEncode implements gob.GobEncoder, serializing the same layer sizes,
+// activations, weights and biases as SaveJSON in gob's binary format
+func (nn *NeuralNetwork) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(nn.data()); err != nil {
+		return nil, fmt.Errorf("nngo: encoding network: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder, replacing nn with the network
+// encoded by GobEncode
+func (nn *NeuralNetwork) GobDecode(b []byte) error {
+	var doc networkData
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&doc); err != nil {
+		return fmt.Errorf("nngo: decoding network: %w", err)
+	}
+	decoded, err := doc.network()
+	if err != nil {
+		return err
+	}
+	*nn = *decoded
+	return nil
+}
+
+// network validates doc and builds the network it describes
+func (doc *networkData) network() (*NeuralNetwork, error) {
+	if len(doc.LayerSizes) < 2 {
+		return nil, fmt.Errorf("nngo: network has %d layers, need at least 2", len(doc.LayerSizes))
+	}
+	for i, size := range doc.LayerSizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("nngo: layer %d has size %d, must be positive", i, size)
+		}
+	}
+	numLayers := len(doc.LayerSizes) - 1
+	if len(doc.Activations) != numLayers || len(doc.Weights) != numLayers || len(doc.Biases) != numLayers {
+		return nil, fmt.Errorf("nngo: network has %d layers but %d activations, %d weight and %d bias matrices",
+			numLayers, len(doc.Activations), len(doc.Weights), len(doc.Biases))
+	}
+
+	nn := NewNeuralNetwork(doc.LayerSizes)
+	if doc.Loss != "" {
+		loss, ok := lossByName(doc.Loss)
+		if !ok {
+			return nil, fmt.Errorf("nngo: unknown loss %q", doc.Loss)
+		}
+		nn.loss = loss
+	}
+	for l := 0; l < numLayers; l++ {
+		activation, ok := activationByName(doc.Activations[l])
+		if !ok {
+			return nil, fmt.Errorf("nngo: layer %d has unknown activation %q", l+1, doc.Activations[l])
+		}
+		fanIn, width := doc.LayerSizes[l], activation.inputWidth(doc.LayerSizes[l+1])
+		if len(doc.Weights[l]) != width*fanIn {
+			return nil, fmt.Errorf("nngo: layer %d has %d weights, expected %d", l+1, len(doc.Weights[l]), width*fanIn)
+		}
+		if len(doc.Biases[l]) != width {
+			return nil, fmt.Errorf("nngo: layer %d has %d biases, expected %d", l+1, len(doc.Biases[l]), width)
+		}
+		nn.activations[l] = activation
+		nn.weights[l] = mat.NewDense(width, fanIn, doc.Weights[l])
+		nn.biases[l] = mat.NewDense(1, width, doc.Biases[l])
+	}
+
+	if doc.BatchNorms != nil && len(doc.BatchNorms) != numLayers {
+		return nil, fmt.Errorf("nngo: network has %d layers but %d batch normalization entries", numLayers, len(doc.BatchNorms))
+	}
+	for l, bn := range doc.BatchNorms {
+		if len(bn.Gamma) == 0 {
+			continue
+		}
+		width, _ := nn.weights[l].Dims()
+		if len(bn.Gamma) != width || len(bn.Beta) != width || len(bn.RunningMean) != width || len(bn.RunningVar) != width {
+			return nil, fmt.Errorf("nngo: layer %d batch normalization does not match its %d units", l+1, width)
+		}
+		nn.batchNorms[l] = &batchNorm{
+			gamma:       mat.NewDense(1, width, bn.Gamma),
+			beta:        mat.NewDense(1, width, bn.Beta),
+			runningMean: mat.NewDense(1, width, bn.RunningMean),
+			runningVar:  mat.NewDense(1, width, bn.RunningVar),
+		}
+	}
+
+	if doc.TargetMean != nil || doc.TargetStd != nil {
+		outputs := doc.LayerSizes[numLayers]
+		if len(doc.TargetMean) != outputs || len(doc.TargetStd) != outputs {
+			return nil, fmt.Errorf("nngo: target normalization has %d means and %d deviations, network has %d outputs",
+				len(doc.TargetMean), len(doc.TargetStd), outputs)
+		}
+		nn.normalizeTargets = true
+		nn.targetMean, nn.targetStd = doc.TargetMean, doc.TargetStd
+	}
+	for _, tie := range doc.TiedWeights {
+		if err := nn.tieWeights(tie[0], tie[1]); err != nil {
+			return nil, err
+		}
+	}
+	return nn, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// trainedXOR returns a network trained briefly on XOR
+func trainedXOR(t *testing.T, opts ...Option) *NeuralNetwork {
+	t.Helper()
+	inputs, targets := xorData()
+	nn := New([]int{2, 3, 1}, append([]Option{WithSeed(1)}, opts...)...)
+	if _, err := nn.Train(inputs, targets, 500, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	return nn
+}
+
+// assertSamePredictions fails t unless got predicts exactly what want does
+func assertSamePredictions(t *testing.T, got, want *NeuralNetwork) {
+	t.Helper()
+	inputs, _ := xorData()
+	if g, w := got.Predict(inputs), want.Predict(inputs); !mat.Equal(g, w) {
+		t.Errorf("predictions %v, want %v", g.RawMatrix().Data, w.RawMatrix().Data)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	nn := trainedXOR(t)
+	var buf bytes.Buffer
+	if err := nn.SaveJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSamePredictions(t, loaded, nn)
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	nn := trainedXOR(t)
+	b, err := nn.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(NeuralNetwork)
+	if err := decoded.GobDecode(b); err != nil {
+		t.Fatal(err)
+	}
+	for l, w := range decoded.Weights() {
+		wr, wc := w.Dims()
+		if r, c := nn.Weights()[l].Dims(); wr != r || wc != c {
+			t.Errorf("layer %d weights are %dx%d, want %dx%d", l, wr, wc, r, c)
+		}
+	}
+	assertSamePredictions(t, decoded, nn)
+}
+
+func TestJSONRoundTripKeepsLoss(t *testing.T) {
+	for _, loss := range []Loss{MSE{}, MAE{}, Huber{Delta: 0.25}, CrossEntropy{}, CategoricalCrossEntropy{}, SparseCategoricalCrossEntropy{}} {
+		nn := New([]int{2, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(loss))
+		var buf bytes.Buffer
+		if err := nn.SaveJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadJSON(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.loss != loss {
+			t.Errorf("saved loss %#v loaded as %#v", loss, loaded.loss)
+		}
+	}
+
+	inputs, targets := threeClassData()
+	nn := New([]int{2, 4, 3}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(CategoricalCrossEntropy{}))
+	var buf bytes.Buffer
+	if err := nn.SaveJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Train(inputs, targets, 1, 0.5); err != nil {
+		t.Errorf("training the reloaded softmax network: %v", err)
+	}
+
+	if _, err := LoadJSON(strings.NewReader(`{"layerSizes": [1, 1], "activations": ["linear"], "weights": [[1]], "biases": [[0]], "loss": "hinge"}`)); err == nil {
+		t.Error("loading an unknown loss gave no error")
+	}
+}
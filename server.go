@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// predictRequest is the body accepted by POST /predict, one sample per row
+type predictRequest struct {
+	Inputs [][]float64 `json:"inputs"`
+}
+
+// predictResponse is the body returned by POST /predict, one output row
+// per input row
+type predictResponse struct {
+	Outputs [][]float64 `json:"outputs"`
+}
+
+// errorResponse is the body of every failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns PredictHandler(nn). The network must not be trained
+// while the handler is serving.
+func (nn *NeuralNetwork) Handler() http.Handler {
+	return PredictHandler(nn)
+}
+
+// PredictHandler returns an http.Handler serving p for inference:
+//
+//	POST /predict  {"inputs": [[...], ...]} -> {"outputs": [[...], ...]}
+//	GET  /health   {"status": "ok"}
+//
+// Malformed input gets a 400 response with a JSON {"error": ...} body, as
+// does wrong-dimension input when p has a CheckDims method like
+// *NeuralNetwork's.
+func PredictHandler(p Predictor) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/predict", func(w http.ResponseWriter, r *http.Request) {
+		handlePredict(p, w, r)
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "nngo: use GET"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// ListenAndServe serves Handler on addr until the server fails
+func (nn *NeuralNetwork) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, nn.Handler())
+}
+
+// dimsChecker is implemented by predictors that can validate inputs
+// before Predict
+type dimsChecker interface {
+	CheckDims(inputs, targets *mat.Dense) error
+}
+
+func handlePredict(p Predictor, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "nngo: use POST"})
+		return
+	}
+	var req predictRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("nngo: decoding request: %v", err)})
+		return
+	}
+	inputs, err := denseFromRows(req.Inputs)
+	if checker, ok := p.(dimsChecker); ok && err == nil {
+		err = checker.CheckDims(inputs, nil)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	outputs := p.Predict(inputs)
+	rows, _ := outputs.Dims()
+	resp := predictResponse{Outputs: make([][]float64, rows)}
+	for i := range resp.Outputs {
+		resp.Outputs[i] = mat.Row(nil, i, outputs)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// denseFromRows builds a matrix from equal-length, non-empty rows
+func denseFromRows(rows [][]float64) (*mat.Dense, error) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, fmt.Errorf("nngo: no input samples")
+	}
+	cols := len(rows[0])
+	m := mat.NewDense(len(rows), cols, nil)
+	for i, row := range rows {
+		if len(row) != cols {
+			return nil, fmt.Errorf("nngo: input row %d has %d values, row 0 has %d", i, len(row), cols)
+		}
+		m.SetRow(i, row)
+	}
+	return m, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
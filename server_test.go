@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// post sends body to path on h and returns the response status and body
+func post(h http.Handler, path, body string) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec.Code, rec.Body.String()
+}
+
+func TestHandlerPredict(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	code, body := post(nn.Handler(), "/predict", `{"inputs": [[0, 1], [1, 0], [1, 1]]}`)
+	if code != http.StatusOK {
+		t.Fatalf("status %d, body %s", code, body)
+	}
+	var resp predictResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Outputs) != 3 || len(resp.Outputs[0]) != 1 {
+		t.Errorf("outputs %v, want 3 rows of 1", resp.Outputs)
+	}
+
+	for name, bad := range map[string]string{
+		"malformed JSON":  `{"inputs": [[0, 1]`,
+		"ragged rows":     `{"inputs": [[0, 1], [1]]}`,
+		"wrong dimension": `{"inputs": [[0, 1, 2]]}`,
+		"no samples":      `{"inputs": []}`,
+	} {
+		if code, body := post(nn.Handler(), "/predict", bad); code != http.StatusBadRequest || !strings.Contains(body, `"error"`) {
+			t.Errorf("%s: status %d, body %s", name, code, body)
+		}
+	}
+}
+
+func TestHandlerHealth(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewNeuralNetworkWithSeed([]int{2, 1}, 1).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ok"`) {
+		t.Errorf("health: status %d, body %s", rec.Code, rec.Body)
+	}
+}
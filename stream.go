@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// streamBatchSize caps how many queued rows PredictStream runs together
+const streamBatchSize = 64
+
+// PredictStream reads feature vectors from rows, predicts them and sends
+// one output vector per row to out, in order, until rows is closed; it
+// then closes out. Rows already waiting in the channel are predicted
+// together in micro-batches of up to 64, but PredictStream never waits for
+// more rows before predicting those it has, so memory use stays constant
+// and each output is sent as soon as possible. It panics if a row has the
+// wrong number of features.
+func (nn *NeuralNetwork) PredictStream(rows <-chan []float64, out chan<- []float64) {
+	defer close(out)
+	features := nn.layerSizes[0]
+	batch := make([][]float64, 0, streamBatchSize)
+	for row := range rows {
+		batch = append(batch[:0], row)
+	gather:
+		for len(batch) < streamBatchSize {
+			select {
+			case row, ok := <-rows:
+				if !ok {
+					break gather
+				}
+				batch = append(batch, row)
+			default:
+				break gather
+			}
+		}
+
+		inputs := mat.NewDense(len(batch), features, nil)
+		for i, row := range batch {
+			if len(row) != features {
+				panic(fmt.Sprintf("nngo: streamed row has %d features, network expects %d", len(row), features))
+			}
+			inputs.SetRow(i, row)
+		}
+		outputs := nn.Predict(inputs)
+		for i := range batch {
+			out <- mat.Row(nil, i, outputs)
+		}
+	}
+}
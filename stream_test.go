@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestPredictStream(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{3, 4, 2}, 1)
+	inputs := randomDense(1000, 3, 1)
+	want := nn.Predict(inputs)
+
+	rows := make(chan []float64, 100)
+	out := make(chan []float64)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			rows <- mat.Row(nil, i, inputs)
+		}
+		close(rows)
+	}()
+	go nn.PredictStream(rows, out)
+
+	i := 0
+	for got := range out {
+		if i >= 1000 {
+			t.Fatal("stream sent more than 1000 outputs")
+		}
+		if w := want.RawRowView(i); !mat.Equal(mat.NewDense(1, 2, got), mat.NewDense(1, 2, w)) {
+			t.Errorf("row %d: streamed %v, batched %v", i, got, w)
+		}
+		i++
+	}
+	if i != 1000 {
+		t.Errorf("stream sent %d outputs, want 1000", i)
+	}
+}
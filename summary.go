@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Summary returns a text table listing every layer's shape, activation and
+// number of trainable parameters (weights, biases and any batch
+// normalization scales and shifts), followed by the network's total:
+//
+//	Layer    Shape   Activation  Params
+//	dense_1  2 -> 2  sigmoid     6
+//	dense_2  2 -> 1  sigmoid     3
+//	Total trainable params: 9
+func (nn *NeuralNetwork) Summary() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Layer\tShape\tActivation\tParams")
+	total := 0
+	for l := range nn.weights {
+		fanIn, fanOut := nn.layerSizes[l], nn.layerSizes[l+1]
+		params := nn.layerParameters(l)
+		activation := nn.activations[l].Name
+		if nn.batchNorms[l] != nil {
+			activation += " (batch norm)"
+		}
+		total += params
+		fmt.Fprintf(w, "%s\t%d -> %d\t%s\t%d\n", layerName(l), fanIn, fanOut, activation, params)
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "Total trainable params: %d\n", total)
+	return b.String()
+}
+
+// NumParameters returns the number of trainable parameters: every weight
+// and bias plus any batch normalization scales and shifts
+func (nn *NeuralNetwork) NumParameters() int {
+	total := 0
+	for l := range nn.weights {
+		total += nn.layerParameters(l)
+	}
+	return total
+}
+
+// MemoryBytes estimates the memory held by the network's parameters at 8
+// bytes per float64, counting the trainable parameters and the batch
+// normalization running statistics. Optimizer state and training scratch
+// space are not included.
+func (nn *NeuralNetwork) MemoryBytes() int {
+	values := nn.NumParameters()
+	for _, bn := range nn.batchNorms {
+		if bn != nil {
+			_, units := bn.runningMean.Dims()
+			values += 2 * units
+		}
+	}
+	return 8 * values
+}
+
+// layerParameters returns the number of trainable parameters of the
+// layer computed by weights[l]
+func (nn *NeuralNetwork) layerParameters(l int) int {
+	width, fanIn := nn.weights[l].Dims()
+	params := width*fanIn + width
+	if nn.batchNorms[l] != nil {
+		params += 2 * width
+	}
+	return params
+}
+
+// WeightHistogram counts each layer's weights in bins equal-width buckets
+// spanning that layer's smallest to largest weight, keyed by the layer
+// names used by Summary. The largest weight falls in the last bucket, and a
+// layer whose weights are all equal has them all in the first. Biases and
+// NaN or infinite weights are not counted. It panics unless bins is
+// positive.
+func (nn *NeuralNetwork) WeightHistogram(bins int) map[string][]int {
+	if bins <= 0 {
+		panic(fmt.Sprintf("nngo: %d histogram bins, must be positive", bins))
+	}
+	histograms := make(map[string][]int, len(nn.weights))
+	for l, w := range nn.weights {
+		var values []float64
+		for _, v := range mat.DenseCopyOf(w).RawMatrix().Data {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				values = append(values, v)
+			}
+		}
+		counts := make([]int, bins)
+		if len(values) == 0 {
+			histograms[layerName(l)] = counts
+			continue
+		}
+		lo, hi := floats.Min(values), floats.Max(values)
+		for _, v := range values {
+			bin := 0
+			if hi > lo {
+				bin = int(float64(bins) * (v - lo) / (hi - lo))
+				if bin == bins {
+					bin--
+				}
+			}
+			counts[bin]++
+		}
+		histograms[layerName(l)] = counts
+	}
+	return histograms
+}
+
+// layerName names the layer computed by weights[l], counting from 1
+func layerName(l int) string {
+	return fmt.Sprintf("dense_%d", l+1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSummaryParameterCount(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 2, 1}, 1)
+	// 2x2 weights + 2 biases, then 2x1 weights + 1 bias
+	summary := nn.Summary()
+	for _, want := range []string{"dense_1", "2 -> 2", "dense_2", "2 -> 1", "Total trainable params: 9"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary lacks %q:\n%s", want, summary)
+		}
+	}
+	entries := 0
+	for l, w := range nn.Weights() {
+		r, c := w.Dims()
+		_, biases := nn.biases[l].Dims()
+		entries += r*c + biases
+	}
+	if entries != 9 {
+		t.Errorf("network has %d weights and biases, summary reports 9", entries)
+	}
+}
+
+func TestWeightHistogram(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	if err := nn.SetWeights([]*mat.Dense{
+		mat.NewDense(3, 2, []float64{0, 1, 2, 3, 4, 10}),
+		mat.NewDense(1, 3, []float64{5, 5, 5}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	histograms := nn.WeightHistogram(5)
+	if len(histograms) != 2 {
+		t.Fatalf("got %d histograms, want 2", len(histograms))
+	}
+	for l, want := range [][]int{
+		{2, 2, 1, 0, 1},
+		{3, 0, 0, 0, 0},
+	} {
+		got := histograms[layerName(l)]
+		total := 0
+		for i, n := range got {
+			total += n
+			if n != want[i] {
+				t.Errorf("%s bucket %d holds %d weights, want %d", layerName(l), i, n, want[i])
+			}
+		}
+		if r, c := nn.weights[l].Dims(); total != r*c {
+			t.Errorf("%s buckets hold %d weights, layer has %d", layerName(l), total, r*c)
+		}
+	}
+}
+
+func TestWeightHistogramSkipsNonFinite(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 3, 1}, 1)
+	if err := nn.SetWeights([]*mat.Dense{
+		mat.NewDense(3, 2, []float64{0, 1, 2, 3, 4, 10}),
+		mat.NewDense(1, 3, []float64{5, 5, 5}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	nn.weights[0].Set(0, 0, math.NaN())
+	nn.weights[0].Set(0, 1, math.Inf(1))
+	got := nn.WeightHistogram(4)[layerName(0)]
+	want := []int{2, 1, 0, 1}
+	for i, n := range got {
+		if n != want[i] {
+			t.Errorf("bucket %d holds %d weights, want %d", i, n, want[i])
+		}
+	}
+}
+
+func TestNumParameters(t *testing.T) {
+	nn := NewNeuralNetworkWithSeed([]int{2, 2, 1}, 1)
+	// 4 + 2 weights plus 2 + 1 biases
+	if got := nn.NumParameters(); got != 9 {
+		t.Errorf("NumParameters = %d, want 9", got)
+	}
+	if got := nn.MemoryBytes(); got != 72 {
+		t.Errorf("MemoryBytes = %d, want 72", got)
+	}
+	// Batch normalization adds a scale and shift per hidden unit, plus
+	// two running statistics that are not trainable
+	bn := New([]int{2, 2, 1}, WithSeed(1), WithBatchNorm())
+	if got := bn.NumParameters(); got != 13 {
+		t.Errorf("NumParameters with batch norm = %d, want 13", got)
+	}
+	if got := bn.MemoryBytes(); got != 8*17 {
+		t.Errorf("MemoryBytes with batch norm = %d, want %d", got, 8*17)
+	}
+}
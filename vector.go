@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// vectorBuffers holds the per-layer buffers reused by PredictVector
+type vectorBuffers struct {
+	// preActivations[l] and activations[l] hold layer l+1's values
+	preActivations [][]float64
+	activations    [][]float64
+}
+
+// PredictVector runs the feedforward pass on a single sample and returns
+// the network's outputs for it, matching Predict up to rounding. It works
+// on plain slices in buffers kept between calls, so a call allocates
+// little more than the returned slice, which suits low-latency serving of
+// single requests. The buffers make PredictVector unsafe for concurrent
+// use; give each goroutine its own Clone. It panics if input does not
+// match the input layer.
+func (nn *NeuralNetwork) PredictVector(input []float64) []float64 {
+	if want := nn.layerSizes[0]; len(input) != want {
+		panic(fmt.Sprintf("nngo: input has %d features, network expects %d", len(input), want))
+	}
+	buffers := nn.vectorBuffers()
+	in := input
+	for l, w := range nn.weights {
+		width, fanIn := w.Dims()
+		z := buffers.preActivations[l]
+		raw := w.RawMatrix()
+		bias := nn.biases[l].RawRowView(0)
+		for j := 0; j < width; j++ {
+			sum := bias[j]
+			for k, v := range raw.Data[j*raw.Stride : j*raw.Stride+fanIn] {
+				sum += v * in[k]
+			}
+			z[j] = sum
+		}
+		if bn := nn.batchNorms[l]; bn != nil {
+			for j := range z {
+				invStd := 1 / math.Sqrt(bn.runningVar.At(0, j)+batchNormEpsilon)
+				z[j] = bn.gamma.At(0, j)*(z[j]-bn.runningMean.At(0, j))*invStd + bn.beta.At(0, j)
+			}
+		}
+
+		out := buffers.activations[l]
+		switch activation := nn.activations[l]; {
+		case activation.rowFunc != nil:
+			copy(out, activation.rowFunc(mat.NewDense(1, width, z)).RawRowView(0))
+		case activation.pieces > 0:
+			for j := range out {
+				out[j] = z[maxoutWinner(z, j, activation.pieces)]
+			}
+		default:
+			for j, v := range z {
+				out[j] = activation.Func(v)
+			}
+		}
+		in = out
+	}
+
+	outputs := append([]float64(nil), in...)
+	if nn.targetMean != nil {
+		for j := range outputs {
+			outputs[j] = outputs[j]*nn.targetStd[j] + nn.targetMean[j]
+		}
+	}
+	if nn.outputTransform != nil {
+		outputs = nn.outputTransform(mat.NewDense(1, len(outputs), outputs)).RawRowView(0)
+	}
+	return outputs
+}
+
+// vectorBuffers returns the network's PredictVector buffers, allocating
+// them on first use
+func (nn *NeuralNetwork) vectorBuffers() *vectorBuffers {
+	if nn.vector == nil {
+		nn.vector = &vectorBuffers{}
+		for l, w := range nn.weights {
+			width, _ := w.Dims()
+			nn.vector.preActivations = append(nn.vector.preActivations, make([]float64, width))
+			nn.vector.activations = append(nn.vector.activations, make([]float64, nn.layerSizes[l+1]))
+		}
+	}
+	return nn.vector
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPredictVectorMatchesPredict(t *testing.T) {
+	inputs := randomDense(6, 3, 1)
+	targets := randomDense(6, 2, 2)
+	for name, nn := range map[string]*NeuralNetwork{
+		"sigmoid":    New([]int{3, 5, 2}, WithSeed(1)),
+		"maxout":     New([]int{3, 4, 2}, WithSeed(1), WithActivation(Maxout(3))),
+		"softmax":    New([]int{3, 5, 2}, WithSeed(1), WithOutputActivation(SoftmaxOutput), WithLoss(CategoricalCrossEntropy{})),
+		"batch norm": New([]int{3, 5, 2}, WithSeed(1), WithBatchNorm(), WithTargetNormalization()),
+	} {
+		if _, err := nn.Train(inputs, targets, 20, 0.1); err != nil {
+			t.Fatal(err)
+		}
+		want := nn.Predict(inputs)
+		for i := 0; i < 6; i++ {
+			got := nn.PredictVector(inputs.RawRowView(i))
+			for j, v := range got {
+				if math.Abs(v-want.At(i, j)) > 1e-12 {
+					t.Errorf("%s: PredictVector sample %d output %d = %v, Predict gives %v", name, i, j, v, want.At(i, j))
+				}
+			}
+		}
+		assertPanics(t, name+" PredictVector with 2 features", func() { nn.PredictVector([]float64{1, 2}) })
+	}
+}
+
+func BenchmarkPredictVector(b *testing.B) {
+	nn := New([]int{10, 32, 32, 1}, WithSeed(1))
+	input := randomDense(1, 10, 1).RawRowView(0)
+	nn.PredictVector(input)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nn.PredictVector(input)
+	}
+}
+
+func BenchmarkPredictMatrix(b *testing.B) {
+	nn := New([]int{10, 32, 32, 1}, WithSeed(1))
+	input := randomDense(1, 10, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nn.Predict(input)
+	}
+}